@@ -0,0 +1,123 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+var ErrNoUid = errors.New(`ErrNoUid`)
+
+// Accounts 多账号管理，以uid区分各自独立的实例
+type Accounts struct {
+	initf func(api BiliApi)
+	m     map[int]BiliApi
+	lock  sync.RWMutex
+}
+
+// NewAccounts initf用于设置新建的实例，如代理、时区等
+func NewAccounts(initf ...func(api BiliApi)) *Accounts {
+	t := &Accounts{m: make(map[int]BiliApi)}
+	if len(initf) > 0 {
+		t.initf = initf[0]
+	}
+	return t
+}
+
+// New 创建未登记的实例，用于登录新账号，登录后使用Add登记
+func (t *Accounts) New() (api BiliApi) {
+	api = New()
+	if t.initf != nil {
+		t.initf(api)
+	}
+	return
+}
+
+// Add 以cookie中的uid登记实例，已存在时替换
+func (t *Accounts) Add(api BiliApi) (err error, uid int) {
+	if err, uid = Uid(api); err != nil {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.m[uid] = api
+	return
+}
+
+// Get 获取uid对应的实例
+func (t *Accounts) Get(uid int) (api BiliApi, ok bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	api, ok = t.m[uid]
+	return
+}
+
+// Del 移除uid对应的实例
+func (t *Accounts) Del(uid int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.m, uid)
+}
+
+// Uids 所有已登记的uid，升序
+func (t *Accounts) Uids() (uids []int) {
+	t.lock.RLock()
+	for uid := range t.m {
+		uids = append(uids, uid)
+	}
+	t.lock.RUnlock()
+	slices.Sort(uids)
+	return
+}
+
+// Range 按uid升序遍历，f返回false时停止
+func (t *Accounts) Range(f func(uid int, api BiliApi) bool) {
+	for _, uid := range t.Uids() {
+		if api, ok := t.Get(uid); ok && !f(uid, api) {
+			return
+		}
+	}
+}
+
+// Save 将所有账号的cookie以json写入w
+func (t *Accounts) Save(w io.Writer) error {
+	data := make(map[string][]*http.Cookie)
+	t.Range(func(uid int, api BiliApi) bool {
+		data[strconv.Itoa(uid)] = api.GetCookies()
+		return true
+	})
+	return json.NewEncoder(w).Encode(data)
+}
+
+// Load 从r恢复Save保存的账号，已存在的uid将被替换
+//
+// 某项无法恢复时跳过并继续，返回的错误以errors.Join合并各项的错误
+func (t *Accounts) Load(r io.Reader) (err error) {
+	var data map[string][]*http.Cookie
+	if e := json.NewDecoder(r).Decode(&data); e != nil {
+		return e
+	}
+	for key, cookies := range data {
+		api := t.New()
+		api.SetCookies(cookies)
+		if e, _ := t.Add(api); e != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", key, e))
+		}
+	}
+	return
+}
+
+// Uid 从cookie中获取实例登录的uid
+func Uid(api BiliApi) (err error, uid int) {
+	if e, v := api.GetCookie(`DedeUserID`); e != nil {
+		err = ErrNoUid
+	} else if uid, err = strconv.Atoi(v); err != nil {
+		err = errors.Join(ErrNoUid, err)
+	}
+	return
+}
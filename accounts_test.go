@@ -0,0 +1,56 @@
+package biliApi
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAccounts(t *testing.T) {
+	as := NewAccounts()
+
+	a1, a2 := as.New(), as.New()
+	a1.SetCookies([]*http.Cookie{{Name: `DedeUserID`, Value: `1`}, {Name: `SESSDATA`, Value: `a`}})
+	a2.SetCookies([]*http.Cookie{{Name: `DedeUserID`, Value: `2`}, {Name: `SESSDATA`, Value: `b`}})
+	if e, _ := as.Add(as.New()); !errors.Is(e, ErrNoUid) {
+		t.Fatal(e)
+	}
+	for _, a := range []biliApiInter{a1, a2} {
+		if e, _ := as.Add(a); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if e, v := a2.GetCookie(`SESSDATA`); e != nil || v != `b` {
+		t.Fatal(v)
+	}
+
+	var buf bytes.Buffer
+	if e := as.Save(&buf); e != nil {
+		t.Fatal(e)
+	}
+
+	as2 := NewAccounts()
+	if e := as2.Load(&buf); e != nil {
+		t.Fatal(e)
+	}
+	if uids := as2.Uids(); len(uids) != 2 || uids[0] != 1 || uids[1] != 2 {
+		t.Fatal(uids)
+	}
+	if a, ok := as2.Get(1); !ok {
+		t.Fatal()
+	} else if e, v := a.GetCookie(`SESSDATA`); e != nil || v != `a` {
+		t.Fatal(v)
+	}
+
+	// 无uid的项跳过，其余照常恢复
+	as3 := NewAccounts()
+	e := as3.Load(bytes.NewBufferString(`{"1":[{"Name":"DedeUserID","Value":"1"}],"x":[{"Name":"SESSDATA","Value":"c"}],"3":[{"Name":"DedeUserID","Value":"3"}]}`))
+	if !errors.Is(e, ErrNoUid) {
+		t.Fatal(e)
+	}
+	if uids := as3.Uids(); len(uids) != 2 || uids[0] != 1 || uids[1] != 3 {
+		t.Fatal(uids)
+	}
+}
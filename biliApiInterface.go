@@ -1,6 +1,8 @@
 package biliApi
 
 import (
+	"context"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -10,16 +12,37 @@ import (
 
 // copy from target
 
+// BiliApi 即biliApiInter，供包外声明变量、回调时使用
+type BiliApi = biliApiInter
+
 type biliApiInter interface {
 	SetReqPool(pool *pool.Buf[reqf.Req])
 	SetProxy(proxy string)
+	SetProxyPool(p *ProxyPool) // 设置代理池，设置后SetProxy不再生效，nil时取消
 	SetDisableSystemProxy(disableSystemProxy bool)
-	SetLocation(secOfTimeZone int)                        // east positive
-	SetCookies(cookies []*http.Cookie, overwrite ...bool) // 设置bili cookie，用于从cookie持久化中恢复
-	SetCookiesCallback(func(cookies []*http.Cookie))      // 当有新cookie时，将调用，用于cookie持久化
-	GetCookies() (cookies []*http.Cookie)                 // 获取所有cookie，用于其他需要cookie的情况
-	GetCookie(name string) (error, string)                // 获取特定cookie，用于其他需要cookie的情况
-	IsLogin() bool                                        // 通过cookie判断是否登录
+	SetLocation(secOfTimeZone int)                           // east positive
+	GetLocation() *time.Location                             // 获取SetLocation设置的时区，默认UTC
+	SetFingerprintActivate(activate bool)                    // 首次请求前将自动生成设备指纹(buvid3等)，设置生成后是否激活buvid
+	SetCookies(cookies []*http.Cookie, overwrite ...bool)    // 设置bili cookie，用于从cookie持久化中恢复
+	SetCookiesCallback(func(cookies []*http.Cookie))         // 当有新cookie时，将调用，用于cookie持久化
+	GetCookies() (cookies []*http.Cookie)                    // 获取所有cookie，用于其他需要cookie的情况
+	GetCookie(name string) (error, string)                   // 获取特定cookie，用于其他需要cookie的情况
+	GetCookiesExpires() (name string, expires time.Time)     // 获取最早过期的cookie，用于提醒重新登录，均为会话cookie时返回零值
+	IsLogin() bool                                           // 通过cookie判断是否登录
+	AddMiddleware(m Middleware)                              // 添加请求中间件，用于日志、修改请求头等
+	SetIdent(ident Ident)                                    // 设置UA、sec-ch-ua等客户端标识，用于整体轮换
+	SetHeaderProfile(api string, p HeaderProfile)            // 设置方法使用的请求头模板，api为方法名
+	SetRateLimit(family ApiFamily, limit RateLimit)          // 设置接口族的令牌桶限速
+	GetRateLimitStats() (stats map[ApiFamily]RateLimitStats) // 获取各接口族的限速统计
+	SetContext(ctx context.Context)                          // 设置实例的上下文，取消时排队中的请求将返回
+	SetRetryPolicy(api string, p RetryPolicy)                // 设置方法的重试策略，api为方法名
+	SetBreaker(family ApiFamily, conf BreakerConf)           // 设置接口族的熔断，出现-352/-412等风控响应时暂停请求
+	GetBreakerState() (states map[ApiFamily]BreakerState)    // 获取各接口族的熔断状态，用于监控
+	SetMetrics(m Metrics)                                    // 设置指标收集，每次请求结束后调用，可使用NewMetricsCollector
+	SetLogger(l *slog.Logger)                                // 设置日志，以debug级别记录每次请求，SESSDATA、bili_jct等将被隐去
+	SetCacheTTL(api string, ttl time.Duration)               // 设置只读方法的缓存时间，api为方法名，<=0时不缓存，缓存的结果为各调用者共用，不应修改其中的切片、map
+	InvalidateCache(api ...string)                           // 清除缓存，api为空时清除全部，cookie变化时将自动清除全部
+	With(opt CallOpt) BiliApi                                // 返回以opt调用的实例，与原实例共用cookie、配置等，用于单次调用，如跳过缓存、指定代理
 
 	LikeReport(hitCount, uid, roomid, upUid int) (err error)
 	LoginQrCode() (err error, imgUrl string, QrcodeKey string)
@@ -91,10 +114,12 @@ type biliApiInter interface {
 		}
 	})
 	GenWebTicket() (err error)
+	GenFingerprint(activate bool) (err error)
 	Wbi(query string, WbiImg struct {
 		ImgURL string
 		SubURL string
 	}) (err error, queryEnc string)
+	WbiRefresh() (err error) // 强制刷新wbi key，用于-352后
 	GetWearedMedal(uid, upUid int) (err error, res struct {
 		TodayIntimacy int
 		RoomID        int
@@ -108,6 +133,8 @@ type biliApiInter interface {
 		RoomID       int
 		LivingStatus int
 	})
+	RangeFansMedal(filter FansMedalFilter, f func(m FansMedal) (next bool)) (err error) // 依次获取所有页的粉丝牌，f返回false时停止
+	GetFansMedals(filter FansMedalFilter) (err error, res []FansMedal)                  // 获取所有粉丝牌
 	SetFansMedal(medalId int) (err error)
 	GetWebGetSignInfo() (err error, Status int)
 	DoSign() (err error, HadSignDays int)
@@ -118,6 +145,10 @@ type biliApiInter interface {
 		Gift_num  int
 		Expire_at int
 	})
+	SendBagGift(roomid, upUid, bagID, giftID, num int) (err error, res GiftResult)              // 赠送背包礼物
+	SendGift(roomid, upUid, giftID, num int, coinType string) (err error, res GiftResult)       // 以金/银瓜子赠送礼物，coinType为CoinGold或CoinSilver
+	SendExpiringBagGifts(roomid, upUid int, within time.Duration) (err error, res []GiftResult) // 将within内过期的背包礼物全部赠送至直播间
+	GetGiftConfig(roomid int) (err error, res GiftConfigs)                                      // 获取直播间可用的礼物列表，按直播间及分区缓存，InvalidateCache(`GetGiftConfig`)以刷新
 	GetWalletStatus() (err error, res struct {
 		Silver          int
 		Silver2CoinLeft int
@@ -130,6 +161,8 @@ type biliApiInter interface {
 		Roomid     int
 		LiveStatus int
 	})
+	RangeHistory(typ HistoryType, f func(h HistoryItem) (next bool)) (err error) // 依次获取所有历史记录，typ为空时为全部，f返回false时停止
+	DeleteHistory(business string, oid int) (err error)                          // 删除一条历史记录
 	RoomEntryAction(Roomid int) (err error)
 	QueryContributionRank(upUid, roomid int) (err error, OnlineNum int)
 	GetOnlineGoldRank(upUid, roomid int) (err error, OnlineNum int)
@@ -139,8 +172,18 @@ type biliApiInter interface {
 		Title      string
 		LiveStatus int
 	})
+	RangeFollowing(pageSize int, f func(r FollowingRoom) (next bool)) (err error) // 依次获取所有关注的主播，pageSize<=0时为10，f返回false时停止
+	GetUserCard(uid int) (err error, res UserCard)                                // 用户名片
+	GetSpaceInfo(uid int) (err error, res SpaceInfo)                              // 用户空间信息，含直播间
+	GetRoomidByUid(uid int) (err error, roomid int)                               // 主播的直播间号，未开通时为0
+	Follow(uid int) (err error)                                                   // 关注，已关注时不返回错误
+	Unfollow(uid int) (err error)                                                 // 取消关注
+	GetRelation(uid int) (err error, res Relation)                                // 与uid的双向关系
+	GetRelations(uids ...int) (err error, res map[int]Relation)                   // 批量获取关系，FollowedBy仅在互相关注时为true
 	IsConnected() (err error)
 	GetHisDanmu(Roomid int) (err error, res []string)
+	SendDanmu(Roomid int, msg string) (err error)                       // 发送弹幕
+	Search(keyword string, opt SearchOpt) (err error, res SearchResult) // 搜索直播间、主播、用户、视频
 	SearchUP(s string) (err error, res []struct {
 		Roomid  int
 		Uname   string
@@ -0,0 +1,253 @@
+package biliApi
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrBreakerOpen = errors.New(`ErrBreakerOpen`)
+
+// BreakerOpenError 熔断中的请求返回的错误，errors.Is(err, ErrBreakerOpen)为true
+type BreakerOpenError struct {
+	Family ApiFamily
+	Code   int       // 触发熔断的业务码，http 412时为412
+	Until  time.Time // 冷却结束时间
+}
+
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("ErrBreakerOpen: %s by %d until %s", e.Family, e.Code, e.Until.Format(time.DateTime))
+}
+
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrBreakerOpen
+}
+
+// BreakerConf 熔断配置
+type BreakerConf struct {
+	Threshold int           // 连续风控响应达到此数时熔断，<=0时不熔断
+	CoolDown  time.Duration // 熔断持续时间，之后进入半开
+	Codes     []int         // 视为风控的业务码，http 412总是视为风控
+	ProbeApi  string        // 半开时在后台以此方法探测，期间其他请求返回熔断错误，支持主站的IsConnected、GetNav，空时以首个请求作为探测
+}
+
+var defaultBreakerConf = map[ApiFamily]BreakerConf{
+	FamilyLive:     {Threshold: 3, CoolDown: 5 * time.Minute, Codes: []int{-352, -412}},
+	FamilyMain:     {Threshold: 3, CoolDown: 5 * time.Minute, Codes: []int{-352, -412}, ProbeApi: `GetNav`},
+	FamilyPassport: {Threshold: 3, CoolDown: 5 * time.Minute, Codes: []int{-352, -412}},
+}
+
+// 可用作探测的方法及其所属接口族
+var breakerProbes = map[string]ApiFamily{
+	`IsConnected`: FamilyMain,
+	`GetNav`:      FamilyMain,
+}
+
+// 半开时自身即作为探测的方法
+// 这些请求在生成设备指纹、bili_ticket时持锁发出，若等待ProbeApi探测，探测请求将再次等待该锁
+var breakerSelfProbeApis = append([]string{`GenWebTicket`}, fingerprintApis...)
+
+// BreakerStatus 熔断状态
+type BreakerStatus int
+
+const (
+	BreakerClosed   BreakerStatus = iota // 正常
+	BreakerOpen                          // 熔断中
+	BreakerHalfOpen                      // 冷却结束，等待探测
+)
+
+func (t BreakerStatus) String() string {
+	switch t {
+	case BreakerOpen:
+		return `open`
+	case BreakerHalfOpen:
+		return `half-open`
+	default:
+		return `closed`
+	}
+}
+
+// BreakerState 熔断器状态，用于监控
+type BreakerState struct {
+	Status   BreakerStatus
+	Failures int       // 连续风控响应数
+	Code     int       // 最近的风控业务码
+	Until    time.Time // 熔断中时为冷却结束时间
+	Trips    uint64    // 累计熔断次数
+}
+
+// 请求结果
+const (
+	breakerNeutral = iota // 网络错误、5xx等，不能说明是否被风控
+	breakerSuccess
+	breakerRisk
+)
+
+type breaker struct {
+	family  ApiFamily
+	conf    BreakerConf
+	state   BreakerState
+	probing bool // 半开时已有探测请求
+	probeGo bool // 已在后台执行ProbeApi
+	lock    sync.Mutex
+}
+
+func (t *breaker) openErr() error {
+	return &BreakerOpenError{Family: t.family, Code: t.state.Code, Until: t.state.Until}
+}
+
+// allow 判断api能否请求，probe为true时该请求即为探测
+// runProbe非空时调用者应在请求外执行该方法探测，结束后调用probeEnd
+func (t *breaker) allow(api string, now time.Time) (probe bool, runProbe string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.state.Status == BreakerOpen {
+		if now.Before(t.state.Until) {
+			return false, ``, t.openErr()
+		}
+		t.state.Status = BreakerHalfOpen
+		t.probing = false
+	}
+	if t.state.Status == BreakerHalfOpen {
+		if t.probing {
+			return false, ``, t.openErr()
+		} else if t.conf.ProbeApi != `` && api != t.conf.ProbeApi && !slices.Contains(breakerSelfProbeApis, api) {
+			if t.probeGo {
+				return false, ``, t.openErr()
+			}
+			t.probeGo = true
+			return false, t.conf.ProbeApi, t.openErr()
+		}
+		t.probing = true
+		return true, ``, nil
+	}
+	return false, ``, nil
+}
+
+// probeEnd 后台探测结束
+func (t *breaker) probeEnd() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.probeGo = false
+}
+
+// done 记录请求结果
+func (t *breaker) done(probe bool, result, code int, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if probe {
+		t.probing = false
+	}
+	switch result {
+	case breakerSuccess:
+		t.state.Failures = 0
+		if probe || t.state.Status == BreakerClosed {
+			t.state.Status = BreakerClosed
+			t.state.Until = time.Time{}
+		}
+	case breakerRisk:
+		t.state.Failures += 1
+		t.state.Code = code
+		if t.state.Status != BreakerOpen && (probe || (t.conf.Threshold > 0 && t.state.Failures >= t.conf.Threshold)) {
+			t.state.Status = BreakerOpen
+			t.state.Until = now.Add(t.conf.CoolDown)
+			t.state.Trips += 1
+		}
+	default:
+		if probe {
+			t.state.Status = BreakerOpen
+			t.state.Until = now.Add(t.conf.CoolDown)
+		}
+	}
+}
+
+// result 将响应归类
+func (t *breaker) result(sent bool, statusCode int, code *int) (result, riskCode int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch {
+	case !sent:
+		return breakerNeutral, 0
+	case statusCode == 412:
+		return breakerRisk, 412
+	case code != nil && slices.Contains(t.conf.Codes, *code):
+		return breakerRisk, *code
+	case statusCode > 0 && statusCode < 500:
+		return breakerSuccess, 0
+	default:
+		return breakerNeutral, 0
+	}
+}
+
+func (t *breaker) setConf(conf BreakerConf) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if family, ok := breakerProbes[conf.ProbeApi]; !ok || family != t.family {
+		conf.ProbeApi = ``
+	}
+	t.conf = conf
+	if conf.Threshold <= 0 {
+		t.state = BreakerState{Trips: t.state.Trips}
+		t.probing = false
+	}
+}
+
+func (t *breaker) getState() BreakerState {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.state
+}
+
+// breaker 返回接口族对应的熔断器
+func (t *biliApi) breaker(family ApiFamily) *breaker {
+	t.breakersLock.Lock()
+	defer t.breakersLock.Unlock()
+	if t.breakers == nil {
+		t.breakers = make(map[ApiFamily]*breaker)
+	}
+	b, ok := t.breakers[family]
+	if !ok {
+		b = &breaker{family: family}
+		b.setConf(defaultBreakerConf[family])
+		t.breakers[family] = b
+	}
+	return b
+}
+
+// allowBreaker 熔断判断，冷却结束时按需在后台执行探测
+// 探测不在当前请求中同步执行，当前请求可能持有设备指纹等锁
+func (t *biliApi) allowBreaker(b *breaker, api string) (probe bool, err error) {
+	probe, runProbe, err := b.allow(api, time.Now())
+	if runProbe != `` {
+		go func() {
+			defer b.probeEnd()
+			t.respCache.invalidate(runProbe)
+			switch runProbe {
+			case `IsConnected`:
+				_ = t.IsConnected()
+			case `GetNav`:
+				_, _ = t.GetNav()
+			}
+		}()
+	}
+	return
+}
+
+// SetBreaker implements biliApiInter.
+func (t *biliApi) SetBreaker(family ApiFamily, conf BreakerConf) {
+	t.breaker(family).setConf(conf)
+}
+
+// GetBreakerState implements biliApiInter.
+func (t *biliApi) GetBreakerState() (states map[ApiFamily]BreakerState) {
+	states = make(map[ApiFamily]BreakerState)
+	for _, family := range []ApiFamily{FamilyLive, FamilyMain, FamilyPassport} {
+		states[family] = t.breaker(family).getState()
+	}
+	return
+}
@@ -0,0 +1,216 @@
+package biliApi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	var (
+		n    atomic.Int32
+		risk atomic.Bool
+	)
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		if risk.Load() {
+			_, _ = w.Write([]byte(`{"code":-352,"message":"-352"}`))
+		} else {
+			_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{}}}`))
+		}
+	})
+	defer closef()
+	a.SetBreaker(FamilyLive, BreakerConf{Threshold: 2, CoolDown: 50 * time.Millisecond, Codes: []int{-352}})
+
+	risk.Store(true)
+	for i := 0; i < 2; i++ {
+		if e, _ := a.GetRoomBaseInfo(213); e == nil || errors.Is(e, ErrBreakerOpen) {
+			t.Fatal(e)
+		}
+	}
+	if s := a.GetBreakerState()[FamilyLive]; s.Status != BreakerOpen || s.Code != -352 || s.Trips != 1 {
+		t.Fatal(s)
+	}
+
+	// 熔断中不再请求
+	n.Store(0)
+	e, _ := a.GetRoomBaseInfo(213)
+	var be *BreakerOpenError
+	if !errors.As(e, &be) || be.Family != FamilyLive || n.Load() != 0 {
+		t.Fatal(e, n.Load())
+	}
+	if s := a.GetBreakerState()[FamilyMain]; s.Status != BreakerClosed {
+		t.Fatal(s)
+	}
+
+	// 探测仍为风控，继续熔断
+	time.Sleep(60 * time.Millisecond)
+	if e, _ := a.GetRoomBaseInfo(213); e == nil || errors.Is(e, ErrBreakerOpen) || n.Load() != 1 {
+		t.Fatal(e, n.Load())
+	}
+	if s := a.GetBreakerState()[FamilyLive]; s.Status != BreakerOpen || s.Trips != 2 {
+		t.Fatal(s)
+	}
+
+	// 探测成功，恢复
+	risk.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if e, _ := a.GetRoomBaseInfo(213); e != nil {
+		t.Fatal(e)
+	}
+	if s := a.GetBreakerState()[FamilyLive]; s.Status != BreakerClosed || s.Failures != 0 {
+		t.Fatal(s)
+	}
+}
+
+func TestBreakerProbe(t *testing.T) {
+	b := &breaker{family: FamilyMain}
+	b.setConf(BreakerConf{Threshold: 1, CoolDown: time.Minute, Codes: []int{-412}, ProbeApi: `GetNav`})
+
+	now := time.Now()
+	code := -412
+	if r, c := b.result(true, 200, &code); r != breakerRisk || c != -412 {
+		t.Fatal(r, c)
+	} else {
+		b.done(false, r, c, now)
+	}
+	if r, c := b.result(true, 412, nil); r != breakerRisk || c != 412 {
+		t.Fatal(r, c)
+	}
+
+	if _, _, e := b.allow(`DoSign`, now); !errors.Is(e, ErrBreakerOpen) {
+		t.Fatal(e)
+	}
+
+	// 半开时其他方法返回熔断错误，并仅启动一次后台探测
+	now = now.Add(time.Minute)
+	if probe, runProbe, e := b.allow(`DoSign`, now); probe || runProbe != `GetNav` || e == nil {
+		t.Fatal(probe, runProbe, e)
+	}
+	if probe, runProbe, e := b.allow(`DoSign`, now); probe || runProbe != `` || e == nil {
+		t.Fatal(probe, runProbe, e)
+	}
+	b.probeEnd()
+	if probe, _, e := b.allow(`GetNav`, now); !probe || e != nil {
+		t.Fatal(probe, e)
+	}
+	if _, _, e := b.allow(`GetNav`, now); e == nil {
+		t.Fatal()
+	}
+	b.done(true, breakerSuccess, 0, now)
+	if probe, runProbe, e := b.allow(`DoSign`, now); probe || runProbe != `` || e != nil {
+		t.Fatal(probe, runProbe, e)
+	}
+
+	// 设备指纹的请求自身即为探测
+	b.done(false, breakerRisk, -412, now)
+	now = now.Add(time.Minute)
+	if probe, runProbe, e := b.allow(`getSpi`, now); !probe || runProbe != `` || e != nil {
+		t.Fatal(probe, runProbe, e)
+	}
+	if probe, runProbe, e := b.allow(`GetNav`, now); probe || runProbe != `` || e == nil {
+		t.Fatal(probe, runProbe, e)
+	}
+	b.done(true, breakerSuccess, 0, now)
+
+	// 探测方法不属于该接口族时忽略
+	b = &breaker{family: FamilyLive}
+	b.setConf(BreakerConf{ProbeApi: `GetNav`})
+	if b.conf.ProbeApi != `` {
+		t.Fatal()
+	}
+}
+
+// 设备指纹生成前主站熔断，冷却后的请求不应死锁
+func TestBreakerBeforeFingerprint(t *testing.T) {
+	var (
+		spiRisk  atomic.Bool
+		cardRisk atomic.Bool
+		nav      atomic.Int32
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/x/frontend/finger/spi`:
+			if spiRisk.Load() {
+				_, _ = w.Write([]byte(`{"code":-352,"message":"-352"}`))
+			} else {
+				_, _ = w.Write([]byte(`{"code":0,"data":{"b_3":"b3","b_4":"b4"}}`))
+			}
+		case `/x/web-interface/card`:
+			if cardRisk.Load() {
+				_, _ = w.Write([]byte(`{"code":-352,"message":"-352"}`))
+			} else {
+				_, _ = w.Write([]byte(`{"code":0,"data":{"card":{"name":"n"}}}`))
+			}
+		case `/x/web-interface/nav`:
+			nav.Add(1)
+			_, _ = w.Write([]byte(`{"code":0,"data":{"isLogin":false}}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":0}`))
+		}
+	}))
+	defer s.Close()
+	a := newBiliApi(newReqPool())
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			u, e := url.Parse(req.Url)
+			if e == nil {
+				req.Url = s.URL + u.RequestURI()
+			}
+			return e
+		},
+	})
+	a.SetBreaker(FamilyMain, BreakerConf{Threshold: 1, CoolDown: 50 * time.Millisecond, Codes: []int{-352}, ProbeApi: `GetNav`})
+
+	spiRisk.Store(true)
+	if e, _ := a.GetUserCard(1); !errors.Is(e, ErrBreakerOpen) {
+		t.Fatal(e)
+	}
+	if s := a.GetBreakerState()[FamilyMain]; s.Status != BreakerOpen {
+		t.Fatal(s)
+	}
+
+	// 冷却后重新生成设备指纹，getSpi即为探测
+	spiRisk.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	a.fpLock.Lock()
+	a.fpRetry = time.Time{}
+	a.fpLock.Unlock()
+	done := make(chan error)
+	go func() {
+		e, _ := a.GetUserCard(2)
+		done <- e
+	}()
+	select {
+	case e := <-done:
+		if e != nil || !a.fpDone.Load() || nav.Load() != 0 {
+			t.Fatal(e, nav.Load())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal(`deadlock`)
+	}
+
+	// 其他方法在后台以ProbeApi探测
+	cardRisk.Store(true)
+	if e, _ := a.GetUserCard(3); e == nil || errors.Is(e, ErrBreakerOpen) {
+		t.Fatal(e)
+	}
+	cardRisk.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if e, _ := a.GetUserCard(4); !errors.Is(e, ErrBreakerOpen) {
+		t.Fatal(e)
+	}
+	for i := 0; a.GetBreakerState()[FamilyMain].Status != BreakerClosed; i++ {
+		if i > 100 {
+			t.Fatal(a.GetBreakerState()[FamilyMain])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if e, _ := a.GetUserCard(4); e != nil || nav.Load() != 1 {
+		t.Fatal(e, nav.Load())
+	}
+}
@@ -0,0 +1,157 @@
+package biliApi
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 各只读方法默认的缓存时间，未列出的不缓存
+var apiCacheTTL = map[string]time.Duration{
+	`GetNav`:                  time.Minute,
+	`GetRoomBaseInfo`:         5 * time.Second,
+	`GetInfoByRoom`:           5 * time.Second,
+	`GetGuardNum`:             30 * time.Second,
+	`GetPopularAnchorRank`:    10 * time.Second,
+	`GetDanmuMedalAnchorInfo`: time.Minute,
+	`GetWalletRule`:           10 * time.Minute,
+	`GetGiftConfig`:           10 * time.Minute,
+	`GetUserCard`:             time.Minute,
+	`GetSpaceInfo`:            time.Minute,
+	`GetRoomidByUid`:          time.Hour,
+}
+
+// 缓存条目数超过此数时，清理过期条目
+const respCacheSweep = 1024
+
+type respCacheEntry struct {
+	api     string
+	val     any
+	gen     uint64
+	expires time.Time
+}
+
+type respCacheCall struct {
+	done chan struct{}
+	val  any
+	err  error
+}
+
+// respCache 以方法名+参数为键的响应缓存，相同的并发请求只执行一次
+type respCache struct {
+	gen     uint64 // cookie变化时递增，使之前的缓存失效
+	entries map[string]*respCacheEntry
+	calls   map[string]*respCacheCall
+	lock    sync.Mutex
+}
+
+// invalidate 清除api的缓存，api为空时清除全部
+func (t *respCache) invalidate(api ...string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(api) == 0 {
+		t.gen += 1
+		return
+	}
+	for k, v := range t.entries {
+		for _, a := range api {
+			if v.api == a {
+				delete(t.entries, k)
+			}
+		}
+	}
+}
+
+// cacheKey 以\x00分隔各参数的%#v，使不同的参数不会得到相同的键
+func cacheKey(api string, args ...any) string {
+	var b strings.Builder
+	b.WriteString(api)
+	for _, arg := range args {
+		b.WriteByte(0)
+		fmt.Fprintf(&b, "%#v", arg)
+	}
+	return b.String()
+}
+
+// cacheDo 查找缓存，未命中时调用者应执行请求并在结束后调用done
+// 有相同请求正在进行时等待其结果，hit为true时res、err即为结果
+// 结果为各调用者共用，其中的切片、map不应修改
+func cacheDo[T any](t *biliApi, c *biliApiConf, res *T, api string, args ...any) (err error, hit bool, done func(err error)) {
+	ttl, ok := c.cacheTTLs[api]
+	if !ok {
+		ttl = apiCacheTTL[api]
+	}
+	if ttl <= 0 || t.opt.NoCache {
+		return
+	}
+
+	key := cacheKey(api, args...)
+	cache := &t.respCache
+
+	cache.lock.Lock()
+	now := time.Now()
+	if e, ok := cache.entries[key]; ok {
+		if e.gen == cache.gen && now.Before(e.expires) {
+			*res = e.val.(T)
+			cache.lock.Unlock()
+			return nil, true, nil
+		}
+		delete(cache.entries, key)
+	}
+	if call, ok := cache.calls[key]; ok {
+		cache.lock.Unlock()
+		<-call.done
+		if call.err == nil {
+			*res = call.val.(T)
+		}
+		return call.err, true, nil
+	}
+	call := &respCacheCall{done: make(chan struct{})}
+	if cache.calls == nil {
+		cache.calls = make(map[string]*respCacheCall)
+	}
+	cache.calls[key] = call
+	cache.lock.Unlock()
+
+	return nil, false, func(err error) {
+		call.val, call.err = *res, err
+
+		cache.lock.Lock()
+		delete(cache.calls, key)
+		if err == nil {
+			if cache.entries == nil {
+				cache.entries = make(map[string]*respCacheEntry)
+			}
+			if len(cache.entries) >= respCacheSweep {
+				now := time.Now()
+				maps.DeleteFunc(cache.entries, func(_ string, v *respCacheEntry) bool {
+					return v.gen != cache.gen || !now.Before(v.expires)
+				})
+			}
+			// 方法自身可能改变cookie，以结束时的gen为准
+			cache.entries[key] = &respCacheEntry{api: api, val: *res, gen: cache.gen, expires: time.Now().Add(ttl)}
+		}
+		cache.lock.Unlock()
+
+		close(call.done)
+	}
+}
+
+// SetCacheTTL implements biliApiInter.
+func (t *biliApi) SetCacheTTL(api string, ttl time.Duration) {
+	t.setConfig(func(c *biliApiConf) {
+		c.cacheTTLs = maps.Clone(c.cacheTTLs)
+		if c.cacheTTLs == nil {
+			c.cacheTTLs = make(map[string]time.Duration)
+		}
+		c.cacheTTLs[api] = ttl
+	})
+	t.respCache.invalidate(api)
+}
+
+// InvalidateCache implements biliApiInter.
+func (t *biliApi) InvalidateCache(api ...string) {
+	t.respCache.invalidate(api...)
+}
@@ -0,0 +1,83 @@
+package biliApi
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRespCache(t *testing.T) {
+	var n atomic.Int32
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{"213":{"room_id":213,"uid":1,"title":"t"}}}}`))
+	})
+	defer closef()
+	a.SetCacheTTL(`GetRoomBaseInfo`, time.Minute)
+
+	// 并发的相同请求只执行一次
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e, res := a.GetRoomBaseInfo(213); e != nil || res.Title != `t` {
+				t.Error(e, res)
+			}
+		}()
+	}
+	wg.Wait()
+	if n.Load() != 1 {
+		t.Fatal(n.Load())
+	}
+
+	// 缓存命中，参数不同时不命中
+	if e, res := a.GetRoomBaseInfo(213); e != nil || res.UpUid != 1 || n.Load() != 1 {
+		t.Fatal(e, n.Load())
+	}
+	if e, _ := a.GetRoomBaseInfo(214); e != nil || n.Load() != 2 {
+		t.Fatal(e, n.Load())
+	}
+
+	// cookie变化时失效
+	a.SetCookies([]*http.Cookie{{Name: `SESSDATA`, Value: `1`}})
+	if e, _ := a.GetRoomBaseInfo(213); e != nil || n.Load() != 3 {
+		t.Fatal(e, n.Load())
+	}
+
+	a.InvalidateCache(`GetRoomBaseInfo`)
+	if e, _ := a.GetRoomBaseInfo(213); e != nil || n.Load() != 4 {
+		t.Fatal(e, n.Load())
+	}
+
+	// 单次调用跳过缓存
+	if e, _ := a.With(CallOpt{NoCache: true}).GetRoomBaseInfo(213); e != nil || n.Load() != 5 {
+		t.Fatal(e, n.Load())
+	}
+	if e, _ := a.GetRoomBaseInfo(213); e != nil || n.Load() != 5 {
+		t.Fatal(e, n.Load())
+	}
+
+	// 不缓存
+	a.SetCacheTTL(`GetRoomBaseInfo`, 0)
+	for i := 0; i < 2; i++ {
+		if e, _ := a.GetRoomBaseInfo(213); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if n.Load() != 7 {
+		t.Fatal(n.Load())
+	}
+}
+
+func TestRespCacheKey(t *testing.T) {
+	if cacheKey(`a`, `1`, 23) == cacheKey(`a`, `12`, 3) {
+		t.Fatal()
+	}
+	if cacheKey(`a`, 1, 2) == cacheKey(`a`, `1`, `2`) || cacheKey(`a`, 1, 2) != cacheKey(`a`, 1, 2) {
+		t.Fatal()
+	}
+}
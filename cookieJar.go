@@ -0,0 +1,203 @@
+package biliApi
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 未指定来源时(如从持久化中恢复)，cookie默认作用域
+const defaultCookieDomain = "bilibili.com"
+
+type jarEntry struct {
+	cookie   http.Cookie
+	hostOnly bool
+	expires  time.Time // 零值为会话cookie
+}
+
+func (t *jarEntry) expired(now time.Time) bool {
+	return !t.expires.IsZero() && !now.Before(t.expires)
+}
+
+// cookieJar 按Domain/Path/过期时间管理cookie，非并发安全
+type cookieJar struct {
+	entries []*jarEntry
+}
+
+// set 保存cookie，u为cookie来源，nil时视为bilibili.com下的cookie，返回是否有变化
+func (t *cookieJar) set(u *url.URL, cookies []*http.Cookie, now time.Time) (changed bool) {
+	for _, c := range cookies {
+		if c == nil || c.Name == `` {
+			continue
+		}
+
+		e := &jarEntry{cookie: *c}
+		e.cookie.Raw = ``
+		e.cookie.Unparsed = nil
+
+		// 作用域
+		if domain := strings.ToLower(strings.TrimPrefix(c.Domain, ".")); domain != `` {
+			if u != nil && !domainMatch(u.Hostname(), domain) {
+				continue
+			}
+			e.cookie.Domain = domain
+		} else if u != nil {
+			e.cookie.Domain = strings.ToLower(u.Hostname())
+			e.hostOnly = true
+		} else {
+			e.cookie.Domain = defaultCookieDomain
+		}
+		if e.cookie.Path == `` || e.cookie.Path[0] != '/' {
+			e.cookie.Path = defaultPath(u)
+		}
+
+		// 过期时间，MaxAge优先
+		del := c.Value == ``
+		switch {
+		case c.MaxAge < 0:
+			del = true
+		case c.MaxAge > 0:
+			e.expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			e.expires = c.Expires
+			del = del || !now.Before(c.Expires)
+		}
+		e.cookie.MaxAge = 0
+		e.cookie.Expires = e.expires
+
+		i := slices.IndexFunc(t.entries, func(v *jarEntry) bool {
+			return v.cookie.Name == e.cookie.Name && v.cookie.Domain == e.cookie.Domain && v.cookie.Path == e.cookie.Path
+		})
+		switch {
+		case del && i >= 0:
+			t.entries = slices.Delete(t.entries, i, i+1)
+			changed = true
+		case del:
+		case i >= 0:
+			changed = changed || t.entries[i].cookie.Value != e.cookie.Value || !t.entries[i].expires.Equal(e.expires)
+			t.entries[i] = e
+		default:
+			t.entries = append(t.entries, e)
+			changed = true
+		}
+	}
+	return
+}
+
+// reset 清空cookie
+func (t *cookieJar) reset() (changed bool) {
+	changed = len(t.entries) != 0
+	t.entries = t.entries[:0]
+	return
+}
+
+// del 移除名为names的cookie，返回是否有变化
+func (t *cookieJar) del(names ...string) (changed bool) {
+	l := len(t.entries)
+	t.entries = slices.DeleteFunc(t.entries, func(v *jarEntry) bool {
+		return slices.Contains(names, v.cookie.Name)
+	})
+	return l != len(t.entries)
+}
+
+// purge 移除已过期的cookie，返回是否有变化
+func (t *cookieJar) purge(now time.Time) (changed bool) {
+	l := len(t.entries)
+	t.entries = slices.DeleteFunc(t.entries, func(v *jarEntry) bool {
+		return v.expired(now)
+	})
+	return l != len(t.entries)
+}
+
+// cookies 返回可发往u的未过期cookie，u为nil时返回全部未过期cookie
+func (t *cookieJar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
+	var matched []*jarEntry
+	for _, v := range t.entries {
+		if v.expired(now) {
+			continue
+		}
+		if u != nil {
+			host := strings.ToLower(u.Hostname())
+			if v.hostOnly && host != v.cookie.Domain {
+				continue
+			} else if !v.hostOnly && !domainMatch(host, v.cookie.Domain) {
+				continue
+			} else if !pathMatch(u.EscapedPath(), v.cookie.Path) {
+				continue
+			} else if v.cookie.Secure && u.Scheme != `https` {
+				continue
+			}
+		}
+		matched = append(matched, v)
+	}
+	// 路径更具体的优先
+	slices.SortStableFunc(matched, func(a, b *jarEntry) int {
+		return len(b.cookie.Path) - len(a.cookie.Path)
+	})
+	for _, v := range matched {
+		c := v.cookie
+		cookies = append(cookies, &c)
+	}
+	return
+}
+
+// get 返回名为name的未过期cookie值
+func (t *cookieJar) get(name string, now time.Time) (value string, ok bool) {
+	value, _, ok = t.lookup(name, now)
+	return
+}
+
+// lookup 返回名为name的未过期cookie值及过期时间
+func (t *cookieJar) lookup(name string, now time.Time) (value string, expires time.Time, ok bool) {
+	for _, v := range t.entries {
+		if v.cookie.Name == name && !v.expired(now) {
+			return v.cookie.Value, v.expires, true
+		}
+	}
+	return
+}
+
+// soonest 返回最早过期的未过期cookie，均为会话cookie时返回零值
+func (t *cookieJar) soonest(now time.Time) (name string, expires time.Time) {
+	for _, v := range t.entries {
+		if v.expires.IsZero() || v.expired(now) {
+			continue
+		}
+		if expires.IsZero() || v.expires.Before(expires) {
+			name, expires = v.cookie.Name, v.expires
+		}
+	}
+	return
+}
+
+func domainMatch(host, domain string) bool {
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+func pathMatch(reqPath, cookiePath string) bool {
+	if reqPath == `` {
+		reqPath = `/`
+	}
+	if reqPath == cookiePath {
+		return true
+	}
+	return strings.HasPrefix(reqPath, cookiePath) &&
+		(strings.HasSuffix(cookiePath, `/`) || reqPath[len(cookiePath)] == '/')
+}
+
+func defaultPath(u *url.URL) string {
+	if u == nil {
+		return `/`
+	}
+	p := u.EscapedPath()
+	if p == `` || p[0] != '/' {
+		return `/`
+	}
+	if i := strings.LastIndex(p, `/`); i > 0 {
+		return p[:i]
+	}
+	return `/`
+}
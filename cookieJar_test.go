@@ -0,0 +1,57 @@
+package biliApi
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCookieJar(t *testing.T) {
+	var (
+		jar  cookieJar
+		now  = time.Now()
+		live = &url.URL{Scheme: `https`, Host: `api.live.bilibili.com`, Path: `/xlive/web-room/v1/index/getInfoByRoom`}
+		main = &url.URL{Scheme: `https`, Host: `api.bilibili.com`, Path: `/x/web-interface/nav`}
+	)
+
+	if !jar.set(nil, []*http.Cookie{{Name: `SESSDATA`, Value: `1`, Expires: now.Add(time.Hour)}}, now) {
+		t.Fatal()
+	}
+	if jar.set(nil, []*http.Cookie{{Name: `SESSDATA`, Value: `1`, Expires: now.Add(time.Hour)}}, now) {
+		t.Fatal()
+	}
+	jar.set(live, []*http.Cookie{
+		{Name: `LIVE_BUVID`, Value: `2`},
+		{Name: `bili_jct`, Value: `3`, Domain: `.bilibili.com`, Path: `/`, MaxAge: 60},
+		{Name: `evil`, Value: `4`, Domain: `.example.com`},
+	}, now)
+
+	if cs := jar.cookies(live, now); len(cs) != 3 {
+		t.Fatal(cs)
+	}
+	if cs := jar.cookies(main, now); len(cs) != 2 {
+		t.Fatal(cs)
+	}
+	if _, ok := jar.get(`evil`, now); ok {
+		t.Fatal()
+	}
+	if name, exp := jar.soonest(now); name != `bili_jct` || !exp.Equal(now.Add(time.Minute)) {
+		t.Fatal(name, exp)
+	}
+
+	later := now.Add(2 * time.Minute)
+	if _, ok := jar.get(`bili_jct`, later); ok {
+		t.Fatal()
+	}
+	if !jar.purge(later) || len(jar.entries) != 2 {
+		t.Fatal()
+	}
+
+	if !jar.set(main, []*http.Cookie{{Name: `SESSDATA`, Domain: `bilibili.com`, Path: `/`, MaxAge: -1}}, now) {
+		t.Fatal()
+	}
+	if cs := jar.cookies(nil, now); len(cs) != 1 || cs[0].Name != `LIVE_BUVID` {
+		t.Fatal(cs)
+	}
+}
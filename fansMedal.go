@@ -0,0 +1,183 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// FansMedal 粉丝牌
+type FansMedal struct {
+	MedalID      int
+	MedalName    string
+	Level        int
+	Intimacy     int  // 当前亲密度
+	NextIntimacy int  // 升至下一级所需的亲密度
+	DayLimit     int  // 每日亲密度上限
+	TodayFeed    int  // 今日已获得的亲密度
+	IsLighted    bool // 是否点亮
+	Wearing      bool // 是否佩戴中
+	Special      bool // 位于special_list，即佩戴中或置顶的粉丝牌
+	GuardLevel   int  // 0无 1总督 2提督 3舰长
+	TargetID     int  // 主播uid
+	AnchorName   string
+	RoomID       int // 0为主播无直播间
+	LivingStatus int // 1直播中
+}
+
+// FansMedalFilter 粉丝牌过滤条件，零值不过滤
+type FansMedalFilter struct {
+	RoomID       int   // 直播间
+	TargetIDs    []int // 主播uid
+	LivingStatus []int // 直播状态
+	MinLevel     int
+	MaxLevel     int
+}
+
+func (t FansMedalFilter) match(m FansMedal) bool {
+	switch {
+	case t.RoomID != 0 && m.RoomID != t.RoomID:
+		return false
+	case len(t.TargetIDs) != 0 && !slices.Contains(t.TargetIDs, m.TargetID):
+		return false
+	case len(t.LivingStatus) != 0 && !slices.Contains(t.LivingStatus, m.LivingStatus):
+		return false
+	case t.MinLevel != 0 && m.Level < t.MinLevel:
+		return false
+	case t.MaxLevel != 0 && m.Level > t.MaxLevel:
+		return false
+	default:
+		return true
+	}
+}
+
+type fansMedalItem struct {
+	Medal struct {
+		MedalID       int    `json:"medal_id"`
+		MedalName     string `json:"medal_name"`
+		Level         int    `json:"level"`
+		Intimacy      int    `json:"intimacy"`
+		NextIntimacy  int    `json:"next_intimacy"`
+		DayLimit      int    `json:"day_limit"`
+		TodayFeed     int    `json:"today_feed"`
+		IsLighted     int    `json:"is_lighted"`
+		WearingStatus int    `json:"wearing_status"`
+		GuardLevel    int    `json:"guard_level"`
+		TargetID      int    `json:"target_id"`
+	} `json:"medal"`
+	AnchorInfo struct {
+		NickName string `json:"nick_name"`
+	} `json:"anchor_info"`
+	RoomInfo struct {
+		RoomID       int `json:"room_id"`
+		LivingStatus int `json:"living_status"`
+	} `json:"room_info"`
+}
+
+func (t fansMedalItem) toMedal(special bool) FansMedal {
+	return FansMedal{
+		MedalID:      t.Medal.MedalID,
+		MedalName:    t.Medal.MedalName,
+		Level:        t.Medal.Level,
+		Intimacy:     t.Medal.Intimacy,
+		NextIntimacy: t.Medal.NextIntimacy,
+		DayLimit:     t.Medal.DayLimit,
+		TodayFeed:    t.Medal.TodayFeed,
+		IsLighted:    t.Medal.IsLighted == 1,
+		Wearing:      t.Medal.WearingStatus == 1,
+		Special:      special,
+		GuardLevel:   t.Medal.GuardLevel,
+		TargetID:     t.Medal.TargetID,
+		AnchorName:   t.AnchorInfo.NickName,
+		RoomID:       t.RoomInfo.RoomID,
+		LivingStatus: t.RoomInfo.LivingStatus,
+	}
+}
+
+// RangeFansMedal implements biliApiInter.
+func (t *biliApi) RangeFansMedal(filter FansMedalFilter, f func(m FansMedal) (next bool)) (err error) {
+	c := t.config()
+	if !t.IsLogin() {
+		return ErrNeedLogin
+	}
+
+	r := c.pool.Get()
+	defer c.pool.Put(r)
+
+	// special_list可能在每页重复出现
+	seen := make(map[int]bool)
+	for pageNum := 1; true; pageNum += 1 {
+		url := fmt.Sprintf("https://api.live.bilibili.com/xlive/app-ucenter/v1/fansMedal/panel?page=%d&page_size=10", pageNum)
+		if filter.RoomID != 0 {
+			url += fmt.Sprintf("&room_id=%d", filter.RoomID)
+		}
+		if len(filter.TargetIDs) == 1 {
+			url += fmt.Sprintf("&target_id=%d", filter.TargetIDs[0])
+		}
+
+		err = t.do(c, r, "GetFansMedal", reqf.Rval{
+			Url: url,
+			Header: map[string]string{
+				`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", filter.RoomID),
+			},
+			Timeout: 10 * 1000,
+		})
+		if err != nil {
+			return
+		}
+
+		var j struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    struct {
+				List        []fansMedalItem `json:"list"`
+				SpecialList []fansMedalItem `json:"special_list"`
+				PageInfo    struct {
+					CurrentPage int `json:"current_page"`
+					TotalPage   int `json:"total_page"`
+				} `json:"page_info"`
+			} `json:"data"`
+		}
+
+		err = r.ResponUnmarshal(json.Unmarshal, &j)
+		if err != nil {
+			return
+		} else if j.Code != 0 {
+			return errors.New(j.Message)
+		}
+
+		r.Response(func(r *http.Response) error {
+			t.setRespCookies(r)
+			return nil
+		})
+
+		for i, li := range append(j.Data.SpecialList, j.Data.List...) {
+			m := li.toMedal(i < len(j.Data.SpecialList))
+			if seen[m.MedalID] || !filter.match(m) {
+				continue
+			}
+			seen[m.MedalID] = true
+			if !f(m) {
+				return
+			}
+		}
+
+		if j.Data.PageInfo.CurrentPage >= j.Data.PageInfo.TotalPage {
+			break
+		}
+	}
+	return
+}
+
+// GetFansMedals implements biliApiInter.
+func (t *biliApi) GetFansMedals(filter FansMedalFilter) (err error, res []FansMedal) {
+	err = t.RangeFansMedal(filter, func(m FansMedal) bool {
+		res = append(res, m)
+		return true
+	})
+	return
+}
@@ -0,0 +1,67 @@
+package biliApi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestGetFansMedals(t *testing.T) {
+	var pages []string
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != `/xlive/app-ucenter/v1/fansMedal/panel` {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		page := r.URL.Query().Get(`page`)
+		pages = append(pages, page)
+		special := `{"medal":{"medal_id":1,"medal_name":"一","level":21,"intimacy":100,"next_intimacy":2000,"day_limit":1500,"today_feed":100,"is_lighted":1,"wearing_status":1,"guard_level":3,"target_id":11},"anchor_info":{"nick_name":"甲"},"room_info":{"room_id":101,"living_status":1}}`
+		switch page {
+		case `1`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"special_list":[` + special + `],"list":[
+				{"medal":{"medal_id":2,"medal_name":"二","level":5,"today_feed":0,"is_lighted":0,"target_id":12},"anchor_info":{"nick_name":"乙"},"room_info":{"room_id":102,"living_status":0}}
+			],"page_info":{"current_page":1,"total_page":2}}}`))
+		case `2`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"special_list":[` + special + `],"list":[
+				{"medal":{"medal_id":3,"medal_name":"三","level":12,"today_feed":0,"is_lighted":1,"target_id":13},"anchor_info":{"nick_name":"丙"},"room_info":{"room_id":103,"living_status":1}}
+			],"page_info":{"current_page":2,"total_page":2}}}`))
+		default:
+			t.Error(page)
+		}
+	})
+	defer closef()
+
+	if e, _ := a.GetFansMedals(FansMedalFilter{}); !errors.Is(e, ErrNeedLogin) {
+		t.Fatal(e)
+	}
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}, {Name: `SESSDATA`, Value: `s`}})
+
+	e, res := a.GetFansMedals(FansMedalFilter{})
+	if e != nil || len(res) != 3 || len(pages) != 2 {
+		t.Fatal(e, res, pages)
+	}
+	if m := res[0]; !m.Special || !m.Wearing || !m.IsLighted || m.MedalName != `一` || m.Level != 21 || m.NextIntimacy != 2000 || m.DayLimit != 1500 || m.GuardLevel != 3 || m.AnchorName != `甲` || m.RoomID != 101 {
+		t.Fatal(m)
+	}
+	if m := res[1]; m.Special || m.IsLighted || m.MedalID != 2 {
+		t.Fatal(m)
+	}
+
+	if e, res := a.GetFansMedals(FansMedalFilter{LivingStatus: []int{1}, MaxLevel: 20}); e != nil || len(res) != 1 || res[0].MedalID != 3 {
+		t.Fatal(e, res)
+	}
+	if e, res := a.GetFansMedals(FansMedalFilter{TargetIDs: []int{11, 12}, MinLevel: 6}); e != nil || len(res) != 1 || res[0].MedalID != 1 {
+		t.Fatal(e, res)
+	}
+
+	// 提前停止时不再请求后续页
+	pages = nil
+	if e := a.RangeFansMedal(FansMedalFilter{}, func(m FansMedal) bool { return false }); e != nil || len(pages) != 1 {
+		t.Fatal(e, pages)
+	}
+
+	// 旧接口
+	if e, res := a.GetFansMedal(0, 13); e != nil || len(res) != 1 || res[0].RoomID != 103 || res[0].IsLighted != 1 {
+		t.Fatal(e, res)
+	}
+}
@@ -0,0 +1,228 @@
+package biliApi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// 设备指纹cookie的有效期，同web端
+const fingerprintTTL = 365 * 24 * time.Hour
+
+// 获取失败后，在此时间内不再自动获取
+const fingerprintRetryAfter = time.Minute
+
+var fingerprintCookies = []string{`buvid3`, `buvid4`, `b_nut`, `_uuid`, `b_lsid`}
+
+// SetFingerprintActivate implements biliApiInter.
+func (t *biliApi) SetFingerprintActivate(activate bool) {
+	t.setConfig(func(c *biliApiConf) {
+		c.fingerprintActivate = activate
+	})
+}
+
+// ensureFingerprint 首次请求前生成缺失的设备指纹cookie，失败时不影响请求
+func (t *biliApi) ensureFingerprint() {
+	if t.fpDone.Load() {
+		return
+	}
+
+	t.fpLock.Lock()
+	defer t.fpLock.Unlock()
+	if t.fpDone.Load() || time.Now().Before(t.fpRetry) {
+		return
+	}
+	if e := t.GenFingerprint(t.config().fingerprintActivate); e != nil {
+		t.fpRetry = time.Now().Add(fingerprintRetryAfter)
+		return
+	}
+	t.fpDone.Store(true)
+}
+
+// GenFingerprint implements biliApiInter.
+func (t *biliApi) GenFingerprint(activate bool) (err error) {
+	var (
+		now     = time.Now()
+		expires = now.Add(fingerprintTTL)
+		cookies []*http.Cookie
+		missing = make(map[string]bool)
+	)
+	for _, name := range fingerprintCookies {
+		if e, _ := t.GetCookie(name); e != nil {
+			missing[name] = true
+		}
+	}
+
+	if missing[`buvid3`] || missing[`buvid4`] {
+		if e, b3, b4 := t.getSpi(); e != nil {
+			return e
+		} else {
+			if missing[`buvid3`] {
+				cookies = append(cookies, &http.Cookie{Name: `buvid3`, Value: b3, Path: `/`, Expires: expires})
+			}
+			if missing[`buvid4`] {
+				cookies = append(cookies, &http.Cookie{Name: `buvid4`, Value: b4, Path: `/`, Expires: expires})
+			}
+		}
+	}
+	if missing[`b_nut`] {
+		cookies = append(cookies, &http.Cookie{Name: `b_nut`, Value: strconv.FormatInt(now.Unix(), 10), Path: `/`, Expires: expires})
+	}
+	if missing[`_uuid`] {
+		cookies = append(cookies, &http.Cookie{Name: `_uuid`, Value: genUuid(now), Path: `/`, Expires: expires})
+	}
+	if missing[`b_lsid`] {
+		cookies = append(cookies, &http.Cookie{Name: `b_lsid`, Value: genLsid(now), Path: `/`})
+	}
+	t.SetCookies(cookies)
+
+	if activate && missing[`buvid3`] {
+		err = t.exClimbWuzhi()
+	}
+	return
+}
+
+// getSpi 获取buvid3/buvid4
+func (t *biliApi) getSpi() (err error, b3, b4 string) {
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "getSpi", reqf.Rval{
+		Url:     `https://api.bilibili.com/x/frontend/finger/spi`,
+		Header:  map[string]string{},
+		Timeout: 3 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			B3 string `json:"b_3"`
+			B4 string `json:"b_4"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	} else if j.Data.B3 == `` || j.Data.B4 == `` {
+		err = errors.New(`Data.B3 == "" || Data.B4 == ""`)
+		return
+	}
+	b3, b4 = j.Data.B3, j.Data.B4
+	return
+}
+
+// exClimbWuzhi 激活buvid3
+func (t *biliApi) exClimbWuzhi() (err error) {
+	_, uuid := t.GetCookie(`_uuid`)
+
+	payload, err := json.Marshal(map[string]any{
+		"3064": 1,
+		"5062": strconv.FormatInt(time.Now().UnixMilli(), 10),
+		"03bf": "https%3A%2F%2Fwww.bilibili.com%2F",
+		"39c8": "333.999.fp.risk",
+		"34f1": "",
+		"d402": "",
+		"654a": "",
+		"6e7c": "1920x1080",
+		"3c43": map[string]any{
+			"2673": 0,
+			"5766": 24,
+			"6527": 0,
+			"7003": 1,
+			"807e": 1,
+			"b8ce": t.config().getIdent().UserAgent,
+			"641c": 0,
+			"07a4": "zh-CN",
+			"1c57": 8,
+			"0bd0": 8,
+			"748e": []int{1920, 1080},
+			"d61f": []int{1920, 1040},
+			"fc9d": -480,
+			"6aa9": "Asia/Shanghai",
+			"75b8": 1,
+			"3b21": 1,
+			"8a1c": 0,
+			"d52f": "not available",
+			"adca": "Win32",
+			"80c9": [][]any{},
+			"ed31": 0,
+			"72bd": 0,
+			"097b": 0,
+			"52cd": []int{0, 0, 0},
+		},
+		"54ef": `{"in_new_ab":true,"ab_version":{},"ab_split_num":{}}`,
+		"8b94": "",
+		"df35": uuid,
+		"07a4": "zh-CN",
+		"5f45": nil,
+		"db46": 0,
+	})
+	if err != nil {
+		return
+	}
+	body, err := json.Marshal(map[string]string{"payload": string(payload)})
+	if err != nil {
+		return
+	}
+
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "exClimbWuzhi", reqf.Rval{
+		Url:     `https://api.bilibili.com/x/internal/gaia-gateway/ExClimbWuzhi`,
+		PostStr: string(body),
+		Header: map[string]string{
+			`Content-Type`: `application/json;charset=UTF-8`,
+		},
+		Timeout: 3 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	}
+	return
+}
+
+func randHex(n int) string {
+	b := make([]byte, (n+1)/2)
+	_, _ = rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))[:n]
+}
+
+// genUuid 同web端_uuid，8-4-4-4-12位十六进制+毫秒时间戳后5位+infoc
+func genUuid(now time.Time) string {
+	return fmt.Sprintf("%s-%s-%s-%s-%s%05dinfoc", randHex(8), randHex(4), randHex(4), randHex(4), randHex(12), now.UnixMilli()%100000)
+}
+
+// genLsid 同web端b_lsid，8位十六进制_毫秒时间戳十六进制
+func genLsid(now time.Time) string {
+	return randHex(8) + "_" + strings.ToUpper(strconv.FormatInt(now.UnixMilli(), 16))
+}
@@ -0,0 +1,99 @@
+package biliApi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGenFingerprint(t *testing.T) {
+	a := newBiliApi(newReqPool())
+	a.SetCookies([]*http.Cookie{{Name: `buvid3`, Value: `b3`}, {Name: `buvid4`, Value: `b4`}})
+	if e := a.GenFingerprint(false); e != nil {
+		t.Fatal(e)
+	}
+
+	now := time.Now()
+	for name, re := range map[string]*regexp.Regexp{
+		`_uuid`:  regexp.MustCompile(`^[0-9A-F]{8}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{4}-[0-9A-F]{12}\d{5}infoc$`),
+		`b_lsid`: regexp.MustCompile(`^[0-9A-F]{8}_[0-9A-F]+$`),
+		`b_nut`:  regexp.MustCompile(`^\d{10}$`),
+	} {
+		if e, v := a.GetCookie(name); e != nil || !re.MatchString(v) {
+			t.Fatal(name, v)
+		}
+	}
+
+	_, uuid := a.GetCookie(`_uuid`)
+	if e := a.GenFingerprint(false); e != nil {
+		t.Fatal(e)
+	} else if _, v := a.GetCookie(`_uuid`); v != uuid {
+		t.Fatal(v)
+	}
+	if e, v := a.GetCookie(`buvid3`); e != nil || v != `b3` {
+		t.Fatal(v)
+	}
+	if name, exp := a.GetCookiesExpires(); name == `` || exp.Before(now.Add(fingerprintTTL-time.Minute)) {
+		t.Fatal(name, exp)
+	}
+}
+
+func TestFingerprintAfterNav(t *testing.T) {
+	var navCode atomic.Int32
+	navCode.Store(-101)
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/x/web-interface/nav`:
+			fmt.Fprintf(w, `{"code":%d,"message":"m","data":{"isLogin":false,"wbi_img":{"img_url":"https://i0.hdslb.com/bfs/wbi/a.png","sub_url":"https://i0.hdslb.com/bfs/wbi/b.png"}}}`, navCode.Load())
+		case `/bapis/bilibili.api.ticket.v1.Ticket/GenWebTicket`:
+			fmt.Fprintf(w, `{"code":0,"data":{"ticket":"tk","created_at":%d,"ttl":259200}}`, time.Now().Unix())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closef()
+	a.SetCookies([]*http.Cookie{{Name: `SESSDATA`, Value: `s`}, {Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}})
+
+	a.ensureFingerprint()
+	if !a.fpDone.Load() {
+		t.Fatal()
+	}
+
+	// 未登录的nav仅移除登录相关的cookie
+	if e, res := a.GetNav(); e != nil || res.IsLogin {
+		t.Fatal(e)
+	}
+	for _, name := range []string{`buvid3`, `buvid4`, `_uuid`, `bili_ticket`} {
+		if e, _ := a.GetCookie(name); e != nil {
+			t.Fatal(name)
+		}
+	}
+	for _, name := range []string{`SESSDATA`, `bili_jct`, `DedeUserID`} {
+		if e, _ := a.GetCookie(name); e == nil {
+			t.Fatal(name)
+		}
+	}
+
+	// 无法判断登录状态时不动cookie
+	navCode.Store(-352)
+	a.SetCookies([]*http.Cookie{{Name: `SESSDATA`, Value: `s`}})
+	if e, _ := a.GetNav(); e == nil {
+		t.Fatal()
+	}
+	if e, v := a.GetCookie(`SESSDATA`); e != nil || v != `s` {
+		t.Fatal(v)
+	}
+
+	// 清空后重新生成设备指纹
+	a.SetCookies(nil, true)
+	if a.fpDone.Load() {
+		t.Fatal()
+	}
+	a.ensureFingerprint()
+	if e, v := a.GetCookie(`buvid3`); e != nil || v != `b3` {
+		t.Fatal(v)
+	}
+}
@@ -0,0 +1,142 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// FollowingRoom 关注的主播，直播中的排在前面
+type FollowingRoom struct {
+	Uid            int
+	Uname          string
+	Face           string
+	Roomid         int
+	Title          string
+	LiveStatus     int // 1直播中
+	AreaID         int
+	AreaName       string
+	ParentAreaID   int
+	ParentAreaName string
+	Online         int       // 直播中时的在线人数
+	LastLive       time.Time // 最近一次开播时间，未知时为零值
+}
+
+// RangeFollowing implements biliApiInter.
+func (t *biliApi) RangeFollowing(pageSize int, f func(r FollowingRoom) (next bool)) (err error) {
+	c := t.config()
+	if !t.IsLogin() {
+		return ErrNeedLogin
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	for pageNum := 1; true; pageNum += 1 {
+		err = t.do(c, req, "GetFollowing", reqf.Rval{
+			Url: fmt.Sprintf("https://api.live.bilibili.com/xlive/web-ucenter/user/following?page=%d&page_size=%d&ignoreRecord=1&hit_ab=true", pageNum, pageSize),
+			Header: map[string]string{
+				`Origin`:  `https://t.bilibili.com`,
+				`Referer`: `https://t.bilibili.com/pages/nav/index_new`,
+			},
+			Timeout: 3 * 1000,
+		})
+		if err != nil {
+			return
+		}
+		var j struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    struct {
+				TotalPage int `json:"totalPage"`
+				List      []struct {
+					Uid            int    `json:"uid"`
+					Uname          string `json:"uname"`
+					Face           string `json:"face"`
+					Roomid         int    `json:"roomid"`
+					Title          string `json:"title"`
+					LiveStatus     int    `json:"live_status"`
+					AreaID         int    `json:"area_id"`
+					AreaName       string `json:"area_name_v2"`
+					ParentAreaID   int    `json:"parent_area_id"`
+					ParentAreaName string `json:"area_name"`
+					Online         int    `json:"online"`
+					RecordLiveTime int64  `json:"record_live_time"`
+				} `json:"list"`
+			} `json:"data"`
+		}
+
+		err = req.ResponUnmarshal(json.Unmarshal, &j)
+		if err != nil {
+			return
+		} else if j.Code != 0 {
+			return errors.New(j.Message)
+		}
+
+		req.Response(func(r *http.Response) error {
+			t.setRespCookies(r)
+			return nil
+		})
+
+		for _, item := range j.Data.List {
+			r := FollowingRoom{
+				Uid:            item.Uid,
+				Uname:          item.Uname,
+				Face:           item.Face,
+				Roomid:         item.Roomid,
+				Title:          item.Title,
+				LiveStatus:     item.LiveStatus,
+				AreaID:         item.AreaID,
+				AreaName:       item.AreaName,
+				ParentAreaID:   item.ParentAreaID,
+				ParentAreaName: item.ParentAreaName,
+				Online:         item.Online,
+			}
+			if item.RecordLiveTime != 0 {
+				r.LastLive = time.Unix(item.RecordLiveTime, 0)
+			}
+			if !f(r) {
+				return
+			}
+		}
+
+		// totalPage为页数
+		if len(j.Data.List) == 0 || pageNum >= j.Data.TotalPage {
+			break
+		}
+	}
+	return
+}
+
+// GetFollowing implements biliApiInter.
+func (t *biliApi) GetFollowing() (err error, res []struct {
+	Roomid     int
+	Uname      string
+	Title      string
+	LiveStatus int
+}) {
+	err = t.RangeFollowing(10, func(r FollowingRoom) bool {
+		if r.LiveStatus != 1 {
+			return false
+		}
+		res = append(res, struct {
+			Roomid     int
+			Uname      string
+			Title      string
+			LiveStatus int
+		}{
+			Roomid:     r.Roomid,
+			Uname:      r.Uname,
+			Title:      r.Title,
+			LiveStatus: r.LiveStatus,
+		})
+		return true
+	})
+	return
+}
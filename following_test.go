@@ -0,0 +1,63 @@
+package biliApi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRangeFollowing(t *testing.T) {
+	var pages []string
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != `/xlive/web-ucenter/user/following` {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		pages = append(pages, q.Get(`page`)+`/`+q.Get(`page_size`))
+		switch q.Get(`page`) {
+		case `1`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"totalPage":3,"list":[
+				{"uid":11,"uname":"甲","face":"https://i0.hdslb.com/a.jpg","roomid":101,"title":"t1","live_status":1,"area_id":371,"area_name_v2":"虚拟主播","parent_area_id":9,"area_name":"虚拟主播","online":1234,"record_live_time":1700000000},
+				{"uid":12,"uname":"乙","roomid":102,"title":"t2","live_status":1}
+			]}}`))
+		case `2`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"totalPage":3,"list":[
+				{"uid":13,"uname":"丙","roomid":103,"title":"t3","live_status":0,"record_live_time":1690000000},
+				{"uid":14,"uname":"丁","roomid":0,"live_status":0}
+			]}}`))
+		case `3`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"totalPage":3,"list":[
+				{"uid":15,"uname":"戊","roomid":105,"live_status":0}
+			]}}`))
+		default:
+			t.Error(q)
+		}
+	})
+	defer closef()
+
+	if e := a.RangeFollowing(0, func(r FollowingRoom) bool { return true }); !errors.Is(e, ErrNeedLogin) {
+		t.Fatal(e)
+	}
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}, {Name: `SESSDATA`, Value: `s`}})
+
+	var res []FollowingRoom
+	if e := a.RangeFollowing(2, func(r FollowingRoom) bool {
+		res = append(res, r)
+		return true
+	}); e != nil || len(res) != 5 || len(pages) != 3 || pages[0] != `1/2` {
+		t.Fatal(e, res, pages)
+	}
+	if r := res[0]; r.Uid != 11 || r.Face == `` || r.AreaID != 371 || r.ParentAreaID != 9 || r.AreaName != `虚拟主播` || r.Online != 1234 || r.LastLive.Unix() != 1700000000 {
+		t.Fatal(r)
+	}
+	if r := res[3]; r.Uid != 14 || r.LiveStatus != 0 || !r.LastLive.IsZero() {
+		t.Fatal(r)
+	}
+
+	// 旧接口仅返回直播中的，遇到未直播的即停止
+	pages = nil
+	if e, res := a.GetFollowing(); e != nil || len(res) != 2 || res[1].Roomid != 102 || len(pages) != 2 {
+		t.Fatal(e, res, pages)
+	}
+}
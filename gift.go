@@ -0,0 +1,262 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+var ErrCoinType = errors.New(`ErrCoinType`)
+
+const (
+	CoinGold   = `gold`   // 金瓜子(电池)
+	CoinSilver = `silver` // 银瓜子
+)
+
+// GiftResult 送礼结果
+type GiftResult struct {
+	Tid         string // 送礼流水号
+	GiftID      int
+	GiftName    string
+	Num         int
+	Price       int    // 单价，单位同CoinType的瓜子
+	CoinType    string // gold、silver
+	TotalCoin   int    // 本次花费
+	LeftNum     int    // 背包中该礼物的剩余数量，仅背包礼物
+	SendTips    string // 如"赠送成功"
+	EffectBlock int    // 为1时不展示特效
+}
+
+// SendBagGift implements biliApiInter.
+func (t *biliApi) SendBagGift(roomid, upUid, bagID, giftID, num int) (err error, res GiftResult) {
+	return t.sendGift(`SendBagGift`, `https://api.live.bilibili.com/xlive/revenue/v1/gift/sendBag`, roomid, upUid, bagID, giftID, num, ``)
+}
+
+// SendGift implements biliApiInter.
+func (t *biliApi) SendGift(roomid, upUid, giftID, num int, coinType string) (err error, res GiftResult) {
+	switch coinType {
+	case CoinGold:
+		return t.sendGift(`SendGift`, `https://api.live.bilibili.com/xlive/revenue/v1/gift/sendGold`, roomid, upUid, 0, giftID, num, coinType)
+	case CoinSilver:
+		return t.sendGift(`SendGift`, `https://api.live.bilibili.com/xlive/revenue/v1/gift/sendSilver`, roomid, upUid, 0, giftID, num, coinType)
+	default:
+		err = ErrCoinType
+		return
+	}
+}
+
+func (t *biliApi) sendGift(api, u string, roomid, upUid, bagID, giftID, num int, coinType string) (err error, res GiftResult) {
+	c := t.config()
+	if !t.IsLogin() {
+		err = ErrNeedLogin
+		return
+	}
+
+	e, csrf := t.GetCookie(`bili_jct`)
+	if e != nil {
+		err = ErrNeedLogin
+		return
+	}
+	e, uid := Uid(t)
+	if e != nil {
+		err = e
+		return
+	}
+
+	postStr := fmt.Sprintf("uid=%d&gift_id=%d&ruid=%d&send_ruid=0&gift_num=%d&bag_id=%d&platform=pc&biz_code=Live&biz_id=%d&storm_beat_id=0&rnd=%d&csrf_token=%s&csrf=%s&visit_id=",
+		uid, giftID, upUid, num, bagID, roomid, time.Now().Unix(), csrf, csrf)
+	if coinType != `` {
+		postStr += `&coin_type=` + coinType
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, api, reqf.Rval{
+		Url:     u,
+		PostStr: postStr,
+		Header: map[string]string{
+			`Content-Type`: `application/x-www-form-urlencoded`,
+			`Referer`:      fmt.Sprintf("https://live.bilibili.com/%d", roomid),
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			TotalCoin int    `json:"total_coin"`
+			SendTips  string `json:"send_tips"`
+			LeftNum   int    `json:"left_num"`
+			GiftList  []struct {
+				Tid         string `json:"tid"`
+				GiftID      int    `json:"gift_id"`
+				GiftName    string `json:"gift_name"`
+				GiftNum     int    `json:"gift_num"`
+				Price       int    `json:"price"`
+				CoinType    string `json:"coin_type"`
+				EffectBlock int    `json:"effect_block"`
+			} `json:"gift_list"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	}
+
+	res.TotalCoin = j.Data.TotalCoin
+	res.SendTips = j.Data.SendTips
+	res.LeftNum = j.Data.LeftNum
+	res.GiftID, res.Num, res.CoinType = giftID, num, coinType
+	if len(j.Data.GiftList) > 0 {
+		g := j.Data.GiftList[0]
+		res.Tid = g.Tid
+		res.GiftID = g.GiftID
+		res.GiftName = g.GiftName
+		res.Num = g.GiftNum
+		res.Price = g.Price
+		res.CoinType = g.CoinType
+		res.EffectBlock = g.EffectBlock
+	}
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
+
+// SendExpiringBagGifts implements biliApiInter.
+func (t *biliApi) SendExpiringBagGifts(roomid, upUid int, within time.Duration) (err error, res []GiftResult) {
+	e, list := t.GetBagList(roomid)
+	if e != nil {
+		return e, nil
+	}
+
+	deadline := time.Now().Add(within).Unix()
+	for _, item := range list {
+		// 0为永久
+		if item.Expire_at <= 0 || int64(item.Expire_at) > deadline || item.Gift_num <= 0 {
+			continue
+		}
+		if e, r := t.SendBagGift(roomid, upUid, item.Bag_id, item.Gift_id, item.Gift_num); e != nil {
+			err = errors.Join(err, fmt.Errorf("%s(%d): %w", item.Gift_name, item.Bag_id, e))
+		} else {
+			res = append(res, r)
+		}
+	}
+	return
+}
+
+// GiftConfig 礼物信息
+type GiftConfig struct {
+	ID         int
+	Name       string
+	Price      int    // 单价，单位同CoinType的瓜子，1000金瓜子=1元
+	CoinType   string // gold、silver
+	ImgBasic   string // 图标
+	ImgDynamic string
+	Gif        string
+	Webp       string
+	Effect     int  // 特效等级
+	BagGift    bool // 是否为背包礼物
+}
+
+// GiftConfigs 礼物列表
+type GiftConfigs []GiftConfig
+
+// Get 按礼物id查找
+func (t GiftConfigs) Get(id int) (gift GiftConfig, ok bool) {
+	for _, v := range t {
+		if v.ID == id {
+			return v, true
+		}
+	}
+	return
+}
+
+// GetGiftConfig implements biliApiInter.
+func (t *biliApi) GetGiftConfig(roomid int) (err error, res GiftConfigs) {
+	e, room := t.GetRoomBaseInfo(roomid)
+	if e != nil {
+		return e, nil
+	}
+	return t.giftConfig(roomid, room.ParentAreaID, room.AreaID)
+}
+
+// giftConfig 礼物列表随分区、直播间变化
+func (t *biliApi) giftConfig(roomid, areaParentID, areaID int) (err error, res GiftConfigs) {
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &res, `GetGiftConfig`, roomid, areaParentID, areaID); hit {
+		return e, res
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetGiftConfig", reqf.Rval{
+		Url: fmt.Sprintf("https://api.live.bilibili.com/xlive/web-room/v1/giftPanel/giftConfig?platform=pc&room_id=%d&area_parent_id=%d&area_id=%d", roomid, areaParentID, areaID),
+		Header: map[string]string{
+			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", roomid),
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			List []struct {
+				ID         int    `json:"id"`
+				Name       string `json:"name"`
+				Price      int    `json:"price"`
+				CoinType   string `json:"coin_type"`
+				ImgBasic   string `json:"img_basic"`
+				ImgDynamic string `json:"img_dynamic"`
+				Gif        string `json:"gif"`
+				Webp       string `json:"webp"`
+				Effect     int    `json:"effect"`
+				BagGift    int    `json:"bag_gift"`
+			} `json:"list"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	}
+
+	for _, v := range j.Data.List {
+		res = append(res, GiftConfig{
+			ID:         v.ID,
+			Name:       v.Name,
+			Price:      v.Price,
+			CoinType:   v.CoinType,
+			ImgBasic:   v.ImgBasic,
+			ImgDynamic: v.ImgDynamic,
+			Gif:        v.Gif,
+			Webp:       v.Webp,
+			Effect:     v.Effect,
+			BagGift:    v.BagGift == 1,
+		})
+	}
+	return
+}
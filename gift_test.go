@@ -0,0 +1,99 @@
+package biliApi
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSendGift(t *testing.T) {
+	var sent []string
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/xlive/web-room/v1/gift/bag_list`:
+			now := time.Now().Unix()
+			fmt.Fprintf(w, `{"code":0,"data":{"list":[
+				{"bag_id":1,"gift_id":30607,"gift_name":"小心心","gift_num":3,"expire_at":%d},
+				{"bag_id":2,"gift_id":1,"gift_name":"辣条","gift_num":5,"expire_at":%d},
+				{"bag_id":3,"gift_id":3,"gift_name":"B坷垃","gift_num":1,"expire_at":0}
+			]}}`, now+3600, now+10*86400)
+		case `/xlive/revenue/v1/gift/sendBag`, `/xlive/revenue/v1/gift/sendGold`:
+			_ = r.ParseForm()
+			if r.PostForm.Get(`csrf`) != `jct` || r.PostForm.Get(`uid`) != `1` || r.PostForm.Get(`ruid`) != `2` || r.PostForm.Get(`biz_id`) != `213` {
+				t.Error(r.PostForm)
+			}
+			sent = append(sent, r.URL.Path+`?bag_id=`+r.PostForm.Get(`bag_id`)+`&coin_type=`+r.PostForm.Get(`coin_type`))
+			fmt.Fprintf(w, `{"code":0,"data":{"total_coin":0,"send_tips":"赠送成功","left_num":0,"gift_list":[{"tid":"t1","gift_id":%s,"gift_name":"小心心","gift_num":%s,"price":0,"coin_type":"silver"}]}}`,
+				r.PostForm.Get(`gift_id`), r.PostForm.Get(`gift_num`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closef()
+
+	if e, _ := a.SendBagGift(213, 2, 1, 30607, 1); e != ErrNeedLogin {
+		t.Fatal(e)
+	}
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}})
+
+	if e, _ := a.SendGift(213, 2, 1, 1, `coin`); e != ErrCoinType {
+		t.Fatal(e)
+	}
+	if e, res := a.SendGift(213, 2, 1, 1, CoinGold); e != nil || res.Tid != `t1` || res.SendTips != `赠送成功` {
+		t.Fatal(e, res)
+	}
+
+	e, res := a.SendExpiringBagGifts(213, 2, 24*time.Hour)
+	if e != nil || len(res) != 1 || res[0].GiftID != 30607 || res[0].Num != 3 {
+		t.Fatal(e, res)
+	}
+	if len(sent) != 2 || sent[0] != `/xlive/revenue/v1/gift/sendGold?bag_id=0&coin_type=gold` || sent[1] != `/xlive/revenue/v1/gift/sendBag?bag_id=1&coin_type=` {
+		t.Fatal(sent)
+	}
+}
+
+func TestGetGiftConfig(t *testing.T) {
+	var n int
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/xlive/web-room/v1/index/getRoomBaseInfo`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{"213":{"room_id":213,"uid":2,"area_id":371,"parent_area_id":9}}}}`))
+		case `/xlive/web-room/v1/giftPanel/giftConfig`:
+			n += 1
+			if q := r.URL.Query(); q.Get(`room_id`) != `213` || q.Get(`area_parent_id`) != `9` || q.Get(`area_id`) != `371` {
+				t.Error(q)
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"list":[
+				{"id":1,"name":"辣条","price":100,"coin_type":"silver","img_basic":"https://i0.hdslb.com/1.png","effect":0,"bag_gift":1},
+				{"id":31036,"name":"小花花","price":100,"coin_type":"gold","gif":"https://i0.hdslb.com/2.gif","effect":2,"bag_gift":0}
+			]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closef()
+
+	e, res := a.GetGiftConfig(213)
+	if e != nil || len(res) != 2 {
+		t.Fatal(e, res)
+	}
+	if g, ok := res.Get(31036); !ok || g.Name != `小花花` || g.CoinType != CoinGold || g.Effect != 2 || g.BagGift {
+		t.Fatal(g)
+	}
+	if g, ok := res.Get(1); !ok || !g.BagGift || g.ImgBasic == `` {
+		t.Fatal(g)
+	}
+	if _, ok := res.Get(2); ok {
+		t.Fatal()
+	}
+
+	// 缓存，清除后刷新
+	if e, _ := a.GetGiftConfig(213); e != nil || n != 1 {
+		t.Fatal(e, n)
+	}
+	a.InvalidateCache(`GetGiftConfig`)
+	if e, _ := a.GetGiftConfig(213); e != nil || n != 2 {
+		t.Fatal(e, n)
+	}
+}
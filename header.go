@@ -0,0 +1,143 @@
+package biliApi
+
+import (
+	"maps"
+)
+
+// Ident 客户端标识，各字段相互对应，应整体替换
+type Ident struct {
+	UserAgent       string
+	SecChUa         string
+	SecChUaMobile   string
+	SecChUaPlatform string
+	AppUserAgent    string // 移动端
+}
+
+var DefaultIdent = Ident{
+	UserAgent:       UA,
+	SecChUa:         `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
+	SecChUaMobile:   `?0`,
+	SecChUaPlatform: `"Windows"`,
+	AppUserAgent:    `Mozilla/5.0 BiliDroid/8.0.0 (bbcallen@gmail.com) os/android model/Pixel 7 mobi_app/android build/8000200 channel/master innerVer/8000200 osVer/14 network/2`,
+}
+
+// HeaderProfile 请求头模板
+type HeaderProfile int
+
+const (
+	HeaderWebLive  HeaderProfile = iota // 直播间页面内的请求
+	HeaderWebMain                       // 主站页面内的请求
+	HeaderPassport                      // 登录相关
+	HeaderWebPage                       // 打开页面
+	HeaderApp                           // 移动端
+)
+
+// 各方法默认使用的模板，未列出的使用HeaderWebLive
+var apiHeaderProfile = map[string]HeaderProfile{
+	`LiveHtml`:        HeaderWebPage,
+	`IsConnected`:     HeaderWebPage,
+	`GetOtherCookies`: HeaderWebPage,
+	`Search`:          HeaderWebMain,
+	`SearchUP`:        HeaderWebMain,
+	`RangeHistory`:    HeaderWebMain,
+	`DeleteHistory`:   HeaderWebMain,
+	`Follow`:          HeaderWebMain,
+	`Unfollow`:        HeaderWebMain,
+	`GetRelation`:     HeaderWebMain,
+	`GetRelations`:    HeaderWebMain,
+	`GetUserCard`:     HeaderWebMain,
+	`GetSpaceInfo`:    HeaderWebMain,
+	`GetNav`:          HeaderWebMain,
+	`GenWebTicket`:    HeaderWebMain,
+	`getSpi`:          HeaderWebMain,
+	`exClimbWuzhi`:    HeaderWebMain,
+	`LoginQrCode`:     HeaderPassport,
+	`LoginQrPoll`:     HeaderPassport,
+	`Logout`:          HeaderPassport,
+}
+
+func (c *biliApiConf) getIdent() Ident {
+	if c.ident.UserAgent == `` {
+		return DefaultIdent
+	}
+	return c.ident
+}
+
+// SetIdent implements biliApiInter.
+func (t *biliApi) SetIdent(ident Ident) {
+	t.setConfig(func(c *biliApiConf) {
+		c.ident = ident
+	})
+}
+
+// SetHeaderProfile implements biliApiInter.
+func (t *biliApi) SetHeaderProfile(api string, p HeaderProfile) {
+	t.setConfig(func(c *biliApiConf) {
+		c.headerProfiles = maps.Clone(c.headerProfiles)
+		if c.headerProfiles == nil {
+			c.headerProfiles = make(map[string]HeaderProfile)
+		}
+		c.headerProfiles[api] = p
+	})
+}
+
+// header 按api对应的模板生成请求头，extra中的项优先
+func (t *biliApi) header(c *biliApiConf, api string, extra map[string]string) (h map[string]string) {
+	p, ok := c.headerProfiles[api]
+	if !ok {
+		p = apiHeaderProfile[api]
+	}
+
+	ident := c.getIdent()
+
+	h = map[string]string{
+		`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
+		`Accept-Encoding`: `gzip, deflate, br`,
+		`Connection`:      `keep-alive`,
+		`Pragma`:          `no-cache`,
+		`Cache-Control`:   `no-cache`,
+	}
+
+	switch p {
+	case HeaderApp:
+		h[`User-Agent`] = ident.AppUserAgent
+		h[`Accept`] = `application/json, text/plain, */*`
+		h[`Accept-Encoding`] = `gzip`
+		h[`APP-KEY`] = `android64`
+		h[`env`] = `prod`
+	case HeaderWebPage:
+		h[`User-Agent`] = ident.UserAgent
+		h[`Accept`] = `text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8`
+		h[`Upgrade-Insecure-Requests`] = `1`
+		h[`Sec-Fetch-Dest`] = `document`
+		h[`Sec-Fetch-Mode`] = `navigate`
+		h[`Sec-Fetch-Site`] = `none`
+		h[`Sec-Fetch-User`] = `?1`
+	default:
+		h[`User-Agent`] = ident.UserAgent
+		h[`Accept`] = `application/json, text/plain, */*`
+		h[`Sec-Fetch-Dest`] = `empty`
+		h[`Sec-Fetch-Mode`] = `cors`
+		h[`Sec-Fetch-Site`] = `same-site`
+		switch p {
+		case HeaderWebMain:
+			h[`Origin`] = `https://www.bilibili.com`
+			h[`Referer`] = `https://www.bilibili.com/`
+		case HeaderPassport:
+			h[`Origin`] = `https://passport.bilibili.com`
+			h[`Referer`] = `https://passport.bilibili.com/login`
+			h[`Sec-Fetch-Site`] = `same-origin`
+		default:
+			h[`Origin`] = `https://live.bilibili.com`
+			h[`Referer`] = `https://live.bilibili.com/`
+		}
+	}
+	if p != HeaderApp && ident.SecChUa != `` {
+		h[`sec-ch-ua`] = ident.SecChUa
+		h[`sec-ch-ua-mobile`] = ident.SecChUaMobile
+		h[`sec-ch-ua-platform`] = ident.SecChUaPlatform
+	}
+
+	maps.Copy(h, extra)
+	return
+}
@@ -0,0 +1,35 @@
+package biliApi
+
+import (
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	a := newBiliApi(newReqPool())
+
+	if h := a.header(a.config(), `GetNav`, nil); h[`Origin`] != `https://www.bilibili.com` || h[`User-Agent`] != UA || h[`sec-ch-ua`] == `` {
+		t.Fatal(h)
+	}
+	if h := a.header(a.config(), `GetGuardNum`, map[string]string{`Referer`: `https://live.bilibili.com/1`}); h[`Origin`] != `https://live.bilibili.com` || h[`Referer`] != `https://live.bilibili.com/1` {
+		t.Fatal(h)
+	}
+	if h := a.header(a.config(), `LiveHtml`, nil); h[`Sec-Fetch-Dest`] != `document` || h[`Origin`] != `` {
+		t.Fatal(h)
+	}
+
+	ident := Ident{
+		UserAgent:       `ua`,
+		SecChUa:         `ch`,
+		SecChUaMobile:   `?0`,
+		SecChUaPlatform: `"Linux"`,
+		AppUserAgent:    `app`,
+	}
+	a.SetIdent(ident)
+	a.SetHeaderProfile(`GetGuardNum`, HeaderApp)
+	if h := a.header(a.config(), `GetNav`, nil); h[`User-Agent`] != `ua` || h[`sec-ch-ua`] != `ch` || h[`sec-ch-ua-platform`] != `"Linux"` {
+		t.Fatal(h)
+	}
+	if h := a.header(a.config(), `GetGuardNum`, nil); h[`User-Agent`] != `app` || h[`sec-ch-ua`] != `` {
+		t.Fatal(h)
+	}
+}
@@ -0,0 +1,226 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// HistoryType 历史记录类型
+type HistoryType string
+
+const (
+	HistoryAll     HistoryType = `all`
+	HistoryArchive HistoryType = `archive` // 视频
+	HistoryLive    HistoryType = `live`
+	HistoryArticle HistoryType = `article` // 专栏
+)
+
+// HistoryItem 历史记录
+type HistoryItem struct {
+	Business   string // archive、pgc、live、article、article-list
+	Oid        int    // 视频avid、直播间号、专栏cvid等
+	Bvid       string
+	Cid        int
+	Title      string
+	Cover      string
+	Uri        string
+	AuthorMid  int // up主、主播uid
+	AuthorName string
+	AuthorFace string
+	ViewAt     time.Time
+	Progress   int    // 观看进度(秒)，-1为已看完
+	Duration   int    // 视频时长(秒)
+	Device     int    // 观看的设备，1、3、5、7手机 2网页 4、6平板 33电视
+	LiveStatus int    // 直播间当前状态，1直播中
+	TagName    string // 分区
+}
+
+// RangeHistory implements biliApiInter.
+func (t *biliApi) RangeHistory(typ HistoryType, f func(h HistoryItem) (next bool)) (err error) {
+	return t.rangeHistory(`RangeHistory`, typ, f)
+}
+
+// rangeHistory api用于中间件、指标、请求头模板等，GetHisStream沿用原名
+func (t *biliApi) rangeHistory(api string, typ HistoryType, f func(h HistoryItem) (next bool)) (err error) {
+	c := t.config()
+	if !t.IsLogin() {
+		return ErrNeedLogin
+	}
+	if typ == `` {
+		typ = HistoryAll
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+
+	// 首页游标为0
+	var (
+		max      int
+		viewAt   int64
+		business string
+	)
+	for {
+		err = t.do(c, req, api, reqf.Rval{
+			Url: fmt.Sprintf("https://api.bilibili.com/x/web-interface/history/cursor?type=%s&ps=20&max=%d&view_at=%d&business=%s", typ, max, viewAt, business),
+			Header: map[string]string{
+				`Origin`:  `https://www.bilibili.com`,
+				`Referer`: `https://www.bilibili.com/account/history`,
+			},
+			Timeout: 5 * 1000,
+		})
+		if err != nil {
+			return
+		}
+		var j struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+			Data    struct {
+				Cursor struct {
+					Max      int    `json:"max"`
+					ViewAt   int64  `json:"view_at"`
+					Business string `json:"business"`
+				} `json:"cursor"`
+				List []struct {
+					Title      string `json:"title"`
+					Cover      string `json:"cover"`
+					Uri        string `json:"uri"`
+					AuthorMid  int    `json:"author_mid"`
+					AuthorName string `json:"author_name"`
+					AuthorFace string `json:"author_face"`
+					ViewAt     int64  `json:"view_at"`
+					Progress   int    `json:"progress"`
+					Duration   int    `json:"duration"`
+					LiveStatus int    `json:"live_status"`
+					TagName    string `json:"tag_name"`
+					History    struct {
+						Oid      int    `json:"oid"`
+						Bvid     string `json:"bvid"`
+						Cid      int    `json:"cid"`
+						Business string `json:"business"`
+						Dt       int    `json:"dt"`
+					} `json:"history"`
+				} `json:"list"`
+			} `json:"data"`
+		}
+
+		err = req.ResponUnmarshal(json.Unmarshal, &j)
+		if err != nil {
+			return
+		} else if j.Code != 0 {
+			return errors.New(j.Message)
+		}
+
+		req.Response(func(r *http.Response) error {
+			t.setRespCookies(r)
+			return nil
+		})
+
+		for _, item := range j.Data.List {
+			if !f(HistoryItem{
+				Business:   item.History.Business,
+				Oid:        item.History.Oid,
+				Bvid:       item.History.Bvid,
+				Cid:        item.History.Cid,
+				Title:      item.Title,
+				Cover:      item.Cover,
+				Uri:        item.Uri,
+				AuthorMid:  item.AuthorMid,
+				AuthorName: item.AuthorName,
+				AuthorFace: item.AuthorFace,
+				ViewAt:     time.Unix(item.ViewAt, 0),
+				Progress:   item.Progress,
+				Duration:   item.Duration,
+				Device:     item.History.Dt,
+				LiveStatus: item.LiveStatus,
+				TagName:    item.TagName,
+			}) {
+				return
+			}
+		}
+
+		// 到底时游标为0
+		cursor := j.Data.Cursor
+		if len(j.Data.List) == 0 || cursor.Max == 0 || (cursor.Max == max && cursor.ViewAt == viewAt && cursor.Business == business) {
+			break
+		}
+		max, viewAt, business = cursor.Max, cursor.ViewAt, cursor.Business
+	}
+	return
+}
+
+// DeleteHistory implements biliApiInter.
+func (t *biliApi) DeleteHistory(business string, oid int) (err error) {
+	c := t.config()
+	if !t.IsLogin() {
+		return ErrNeedLogin
+	}
+	e, csrf := t.GetCookie(`bili_jct`)
+	if e != nil {
+		return ErrNeedLogin
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "DeleteHistory", reqf.Rval{
+		Url:     `https://api.bilibili.com/x/v2/history/delete`,
+		PostStr: `kid=` + url.QueryEscape(business+`_`+strconv.Itoa(oid)) + `&csrf=` + csrf,
+		Header: map[string]string{
+			`Content-Type`: `application/x-www-form-urlencoded`,
+			`Origin`:       `https://www.bilibili.com`,
+			`Referer`:      `https://www.bilibili.com/account/history`,
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		return errors.New(j.Message)
+	}
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
+
+// GetHisStream implements biliApiInter.
+func (t *biliApi) GetHisStream() (err error, res []struct {
+	Uname      string
+	Title      string
+	Roomid     int
+	LiveStatus int
+}) {
+	err = t.rangeHistory(`GetHisStream`, HistoryLive, func(h HistoryItem) bool {
+		res = append(res, struct {
+			Uname      string
+			Title      string
+			Roomid     int
+			LiveStatus int
+		}{
+			Uname:      h.AuthorName,
+			Title:      h.Title,
+			Roomid:     h.Oid,
+			LiveStatus: h.LiveStatus,
+		})
+		return len(res) < 10
+	})
+	return
+}
@@ -0,0 +1,85 @@
+package biliApi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestRangeHistory(t *testing.T) {
+	var cursors, deleted []string
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.URL.Path {
+		case `/x/web-interface/history/cursor`:
+			q := r.URL.Query()
+			if q.Get(`type`) != `live` {
+				t.Error(q)
+			}
+			cursors = append(cursors, q.Get(`max`)+`/`+q.Get(`view_at`)+`/`+q.Get(`business`))
+			switch q.Get(`max`) {
+			case `0`:
+				_, _ = w.Write([]byte(`{"code":0,"data":{"cursor":{"max":102,"view_at":1700000000,"business":"live"},"list":[
+					{"title":"t1","author_mid":11,"author_name":"甲","author_face":"https://i0.hdslb.com/a.jpg","view_at":1700000100,"progress":-1,"live_status":1,"tag_name":"虚拟主播","history":{"oid":101,"business":"live","dt":2}},
+					{"title":"t2","author_mid":12,"author_name":"乙","view_at":1700000000,"live_status":0,"history":{"oid":102,"business":"live","dt":1}}
+				]}}`))
+			case `102`:
+				_, _ = w.Write([]byte(`{"code":0,"data":{"cursor":{"max":0,"view_at":0,"business":""},"list":[
+					{"title":"t3","author_mid":13,"author_name":"丙","view_at":1690000000,"history":{"oid":103,"business":"live","dt":4}}
+				]}}`))
+			default:
+				t.Error(q)
+			}
+		case `/x/v2/history/delete`:
+			if r.PostForm.Get(`csrf`) != `jct` {
+				t.Error(r.PostForm)
+			}
+			deleted = append(deleted, r.PostForm.Get(`kid`))
+			_, _ = w.Write([]byte(`{"code":0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closef()
+
+	if e := a.DeleteHistory(`live`, 101); !errors.Is(e, ErrNeedLogin) {
+		t.Fatal(e)
+	}
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}, {Name: `SESSDATA`, Value: `s`}})
+	apis := make(map[string]bool)
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			apis[req.Api] = true
+			return nil
+		},
+	})
+
+	var res []HistoryItem
+	if e := a.RangeHistory(HistoryLive, func(h HistoryItem) bool {
+		res = append(res, h)
+		return true
+	}); e != nil || len(res) != 3 || len(cursors) != 2 || cursors[1] != `102/1700000000/live` {
+		t.Fatal(e, res, cursors)
+	}
+	if h := res[0]; h.Business != `live` || h.Oid != 101 || h.AuthorMid != 11 || h.AuthorFace == `` || h.ViewAt.Unix() != 1700000100 || h.Progress != -1 || h.Device != 2 || h.TagName != `虚拟主播` {
+		t.Fatal(h)
+	}
+
+	if e, res := a.GetHisStream(); e != nil || len(res) != 3 || res[0].Roomid != 101 || res[0].Uname != `甲` || res[0].LiveStatus != 1 {
+		t.Fatal(e, res)
+	}
+	// 提前停止时不再请求后续页
+	cursors = nil
+	if e := a.RangeHistory(HistoryLive, func(h HistoryItem) bool { return false }); e != nil || len(cursors) != 1 {
+		t.Fatal(e, cursors)
+	}
+
+	if e := a.DeleteHistory(res[1].Business, res[1].Oid); e != nil || len(deleted) != 1 || deleted[0] != `live_102` {
+		t.Fatal(e, deleted)
+	}
+
+	// api为方法名，GetHisStream沿用原名
+	if !apis[`RangeHistory`] || !apis[`GetHisStream`] || !apis[`DeleteHistory`] || apis[`GetHistory`] {
+		t.Fatal(apis)
+	}
+}
@@ -0,0 +1,71 @@
+package biliApi
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// 日志中的响应体最多保留的字节数
+const logBodySnippet = 512
+
+// 日志中需隐去值的字段，匹配k=v(查询、表单、cookie)及"k":"v"(json)
+// json中的&转义为\u0026，其后无单词边界，故显式列出前缀
+var redactRegexp = regexp.MustCompile(`(?i)(^|[^A-Za-z0-9_]|\\u0026)(SESSDATA|bili_jct|csrf_token|csrf|refresh_token|qrcode_key|w_rid)(=|"\s*:\s*")([^&;\s"\\]*)`)
+
+// Redact 隐去s中SESSDATA、bili_jct、csrf等字段的值
+func Redact(s string) string {
+	return redactRegexp.ReplaceAllString(s, `${1}${2}${3}***`)
+}
+
+// SetLogger implements biliApiInter.
+func (t *biliApi) SetLogger(l *slog.Logger) {
+	t.setConfig(func(c *biliApiConf) {
+		c.logger = l
+	})
+}
+
+// logAttempt 以debug级别记录一次请求
+func (c *biliApiConf) logAttempt(ctx context.Context, api, method, url, postStr string, attempt int, statusCode int, code *int, body []byte, duration time.Duration, err error) {
+	if c.logger == nil || !c.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String(`api`, api),
+		slog.String(`method`, method),
+		slog.String(`url`, Redact(url)),
+		slog.Int(`attempt`, attempt),
+		slog.Int(`status`, statusCode),
+		slog.Duration(`duration`, duration),
+	}
+	if postStr != `` {
+		attrs = append(attrs, slog.String(`post`, Redact(postStr)))
+	}
+	if code != nil {
+		attrs = append(attrs, slog.Int(`code`, *code))
+	}
+	if len(body) > 0 {
+		snippet := Redact(string(body))
+		if len(snippet) > logBodySnippet {
+			snippet = snippet[:logBodySnippet] + `...`
+		}
+		attrs = append(attrs, slog.String(`body`, snippet))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String(`err`, Redact(err.Error())))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, `biliApi`, attrs...)
+}
+
+// logError 以warn级别记录不影响调用结果的错误
+func (c *biliApiConf) logError(api string, err error) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.LogAttrs(context.Background(), slog.LevelWarn, `biliApi`,
+		slog.String(`api`, api),
+		slog.String(`err`, Redact(err.Error())),
+	)
+}
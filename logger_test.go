@@ -0,0 +1,55 @@
+package biliApi
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	for s, r := range map[string]string{
+		`a=1&csrf=abc&csrf_token=abc&w_rid=0f`:                                                   `a=1&csrf=***&csrf_token=***&w_rid=***`,
+		`SESSDATA=a%2Cb; bili_jct=c; DedeUserID=1`:                                               `SESSDATA=***; bili_jct=***; DedeUserID=1`,
+		`{"refresh_token":"abc","qrcode_key":"k","code":0}`:                                      `{"refresh_token":"***","qrcode_key":"***","code":0}`,
+		`{"url":"https://a.com/?SESSDATA=1&bili_jct=2&`:                                          `{"url":"https://a.com/?SESSDATA=***&bili_jct=***&`,
+		`{"url":"https://a.com/?DedeUserID=1\u0026SESSDATA=a%2Cb\u0026bili_jct=c\u0026gourl=x"}`: `{"url":"https://a.com/?DedeUserID=1\u0026SESSDATA=***\u0026bili_jct=***\u0026gourl=x"}`,
+		`xSESSDATA=1`: `xSESSDATA=1`,
+	} {
+		if Redact(s) != r {
+			t.Fatal(Redact(s))
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{}},"bili_jct":"secret"}`))
+	})
+	defer closef()
+	a.SetCookies([]*http.Cookie{{Name: `SESSDATA`, Value: `secret`}})
+
+	var buf bytes.Buffer
+	a.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	if e, _ := a.GetRoomBaseInfo(213); e != nil {
+		t.Fatal(e)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, `secret`) {
+		t.Fatal(out)
+	}
+	for _, s := range []string{`api=GetRoomBaseInfo`, `method=GET`, `status=200`, `code=0`, `bili_jct`} {
+		if !strings.Contains(out, s) {
+			t.Fatal(s, out)
+		}
+	}
+
+	// 非debug级别时不记录
+	buf.Reset()
+	a.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	if e, _ := a.GetRoomBaseInfo(213); e != nil || buf.Len() != 0 {
+		t.Fatal(e, buf.String())
+	}
+}
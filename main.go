@@ -2,57 +2,162 @@ package biliApi
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
-	"crypto/md5"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"slices"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	cmp "github.com/qydysky/part/component2"
 	pool "github.com/qydysky/part/pool"
 	reqf "github.com/qydysky/part/reqf"
-	psync "github.com/qydysky/part/sync"
 )
 
 const id = "github.com/qydysky/bili_danmu/F.biliApi"
+
+// bili_ticket未返回ttl时的有效期，及在过期前多久重新生成
+const (
+	webTicketTTL           = 3 * 24 * time.Hour
+	webTicketRefreshBefore = time.Hour
+)
+
 const UA = `Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.3`
 
 var (
 	ErrNeedLogin = errors.New(`ErrNeedLogin`)
 	ErrNoLogin   = errors.New(`ErrNoLogin`)
+	ErrWbiKey    = errors.New(`ErrWbiKey`)
 )
 
+// 登录相关的cookie，nav确认未登录时移除
+var loginCookies = []string{`SESSDATA`, `bili_jct`, `DedeUserID`, `DedeUserID__ckMd5`, `sid`}
+
 func init() {
-	if e := cmp.Register[biliApiInter](id, &biliApi{
-		location: time.UTC,
-	}); e != nil {
+	if e := cmp.Register[biliApiInter](id, newBiliApi(nil)); e != nil {
 		panic(e)
 	}
 }
 
+// New 创建独立的实例，拥有各自的cookie、代理、请求池、缓存及时区，用于多账号
+func New() BiliApi {
+	return newBiliApi(newReqPool())
+}
+
+func newBiliApi(reqPool *pool.Buf[reqf.Req]) *biliApi {
+	t := &biliApi{biliApiState: &biliApiState{}}
+	t.conf.Store(&biliApiConf{
+		location: time.UTC,
+		pool:     reqPool,
+	})
+	return t
+}
+
+func newReqPool() *pool.Buf[reqf.Req] {
+	return pool.New(
+		pool.PoolFunc[reqf.Req]{
+			New: func() *reqf.Req {
+				return reqf.New()
+			},
+			InUse: func(r *reqf.Req) bool {
+				return r.IsLive()
+			},
+			Reuse: func(r *reqf.Req) *reqf.Req {
+				return r
+			},
+			Pool: func(r *reqf.Req) *reqf.Req {
+				return r
+			},
+		},
+		100,
+	)
+}
+
 type biliApi struct {
+	*biliApiState
+	// With设置的调用选项
+	opt CallOpt
+}
+
+// biliApiState 实例的状态，With返回的实例与原实例共用
+type biliApiState struct {
+	conf     atomic.Pointer[biliApiConf]
+	confLock sync.Mutex
+	cookies  cookieJar
+	wbi      wbiSigner
+	// 生成bili_ticket时持有
+	ticketLock sync.Mutex
+	// 设备指纹
+	fpDone  atomic.Bool
+	fpLock  sync.Mutex
+	fpRetry time.Time
+	// 只读方法的响应缓存
+	respCache respCache
+	lock      sync.RWMutex
+	// cookie变化的序号，持有lock时修改
+	cookiesSeq uint64
+	// 依次调用cookiesCallback，仅传递最新的cookie
+	cookiesCb cookiesDelivery
+	// 按接口族限速
+	limiters     map[ApiFamily]*tokenBucket
+	limitersLock sync.Mutex
+	// 按接口族熔断
+	breakers     map[ApiFamily]*breaker
+	breakersLock sync.Mutex
+}
+
+// biliApiConf 实例配置，只读，修改时整体替换
+type biliApiConf struct {
 	proxy              string
 	disableSystemProxy bool
 	location           *time.Location
 	pool               *pool.Buf[reqf.Req]
-	cookies            []*http.Cookie
-	cache              psync.MapExceeded[string, *struct {
-		IsLogin bool
-		WbiImg  struct {
-			ImgURL string
-			SubURL string
-		}
-	}]
-	cookiesCallback func(cookies []*http.Cookie)
-	lock            sync.RWMutex
+	cookiesCallback    func(cookies []*http.Cookie)
+	middlewares        []Middleware
+	ident              Ident
+	headerProfiles     map[string]HeaderProfile
+	// 生成设备指纹后是否激活buvid
+	fingerprintActivate bool
+	// 不生成设备指纹，用于代理健康检查等临时实例
+	fingerprintOff bool
+	// 限速排队、重试等待时使用，nil时不可取消
+	ctx           context.Context
+	retryPolicies map[string]RetryPolicy
+	metrics       Metrics
+	logger        *slog.Logger
+	cacheTTLs     map[string]time.Duration
+	proxyPool     *ProxyPool
+}
+
+// config 返回当前配置的快照，单次请求内应只取一次
+func (t *biliApi) config() *biliApiConf {
+	return t.conf.Load()
+}
+
+func (t *biliApi) setConfig(f func(c *biliApiConf)) {
+	t.confLock.Lock()
+	defer t.confLock.Unlock()
+	c := *t.conf.Load()
+	f(&c)
+	t.conf.Store(&c)
+}
+
+// CallOpt 单次调用的选项，见With
+type CallOpt struct {
+	NoCache bool   // 不读写响应缓存，也不与相同的并发请求合并
+	Proxy   string // 指定代理，优先于代理池及SetProxy，格式同SetProxy
+}
+
+// With implements biliApiInter.
+func (t *biliApi) With(opt CallOpt) BiliApi {
+	return &biliApi{biliApiState: t.biliApiState, opt: opt}
 }
 
 // IsLogin implements biliApiInter.
@@ -67,38 +172,28 @@ func (t *biliApi) IsLogin() bool {
 
 // SetCookiesCallback implements biliApiInter.
 func (t *biliApi) SetCookiesCallback(f func(cookies []*http.Cookie)) {
-	t.cookiesCallback = f
+	t.setConfig(func(c *biliApiConf) {
+		c.cookiesCallback = f
+	})
 }
 
 // LikeReport implements biliApiInter.
 func (t *biliApi) LikeReport(hitCount, uid, roomid, upUid int) (err error) {
+	c := t.config()
 	csrf := ""
 	if e, t := t.GetCookie(`bili_jct`); e == nil {
 		csrf = t
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
-		Url:                "https://api.live.bilibili.com/xlive/app-ucenter/v1/like_info_v3/like/likeReportV3",
-		PostStr:            fmt.Sprintf("click_time=%d&uid=%d&room_id=%d&anchor_id=%d&csrf=%s&csrf_token=%s&visit_id=", hitCount, uid, roomid, upUid, csrf, csrf),
-		Retry:              2,
-		Timeout:            5 * 1000,
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "LikeReport", reqf.Rval{
+		Url:     "https://api.live.bilibili.com/xlive/app-ucenter/v1/like_info_v3/like/likeReportV3",
+		PostStr: fmt.Sprintf("click_time=%d&uid=%d&room_id=%d&anchor_id=%d&csrf=%s&csrf_token=%s&visit_id=", hitCount, uid, roomid, upUid, csrf, csrf),
+		Timeout: 5 * 1000,
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/javascript, */*; q=0.01`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Content-Type`:    `application/x-www-form-urlencoded`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         fmt.Sprintf("https://live.bilibili.com/%d", roomid),
-			`Cookie`:          t.GetCookiesS(),
+			`Content-Type`: `application/x-www-form-urlencoded`,
+			`Referer`:      fmt.Sprintf("https://live.bilibili.com/%d", roomid),
 		},
 	})
 	if err != nil {
@@ -119,7 +214,7 @@ func (t *biliApi) LikeReport(hitCount, uid, roomid, upUid int) (err error) {
 	}
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -127,7 +222,14 @@ func (t *biliApi) LikeReport(hitCount, uid, roomid, upUid int) (err error) {
 
 // SetLocation implements biliApiInter.
 func (t *biliApi) SetLocation(secOfTimeZone int) {
-	t.location = time.FixedZone("CUS", secOfTimeZone)
+	t.setConfig(func(c *biliApiConf) {
+		c.location = time.FixedZone("CUS", secOfTimeZone)
+	})
+}
+
+// GetLocation implements biliApiInter.
+func (t *biliApi) GetLocation() *time.Location {
+	return t.config().location
 }
 
 // LiveHtml implements biliApiInter.
@@ -188,24 +290,14 @@ func (t *biliApi) LiveHtml(Roomid int) (err error, res struct {
 		}
 	}
 }) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "LiveHtml", reqf.Rval{
 		Header: map[string]string{
-			`Host`:            `live.bilibili.com`,
-			`User-Agent`:      `Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.3`,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
+			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
 		},
-		Url:                fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
+		Url: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
 	})
 	if err != nil {
 		return
@@ -347,7 +439,7 @@ func (t *biliApi) LiveHtml(Roomid int) (err error, res struct {
 			}(j)
 
 			req.Response(func(r *http.Response) error {
-				t.SetCookies(r.Cookies())
+				t.setRespCookies(r)
 				return nil
 			})
 		}
@@ -357,107 +449,17 @@ func (t *biliApi) LiveHtml(Roomid int) (err error, res struct {
 
 }
 
-// SearchUP implements biliApiInter.
-func (t *biliApi) SearchUP(s string) (err error, res []struct {
-	Roomid  int
-	Uname   string
-	Is_live bool
-}) {
-
-	query := "gaia_vtoken=&from_source=web_search&page=1&page_size=10&order=online&platform=pc&user_type=1&search_type=live_user&keyword=" + s
-
-	if e, v := t.GetNav(); e != nil {
-		err = e
-		return
-	} else if e, queryE := t.Wbi(query, v.WbiImg); e != nil {
-		err = e
-		return
-	} else {
-		query = queryE
-	}
-
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
-		Method:             "GET",
-		Url:                "https://api.bilibili.com/x/web-interface/wbi/search/type?" + query,
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Header: map[string]string{
-			`Host`:            `api.bilibili.com`,
-			`Accept`:          `*/*`,
-			`Accept-Encoding`: `gzip, deflate, br, zstd`,
-			`Cookie`:          t.GetCookiesS(),
-			`User-Agent`:      UA,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Origin`:          `https://search.bilibili.com/`,
-			`Referer`:         `https://search.bilibili.com/upuser?keyword=&from_source=web_search&spm_id_from=333.1007&search_source=5`,
-		},
-		Timeout: 10 * 1000,
-		Retry:   2,
-	})
-	if err != nil {
-		return
-	}
-
-	var j struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		TTL     int    `json:"ttl"`
-		Data    struct {
-			Result []struct {
-				IsLive int    `json:"live_status"`
-				Uname  string `json:"uname"`
-				Roomid int    `json:"roomid"`
-			} `json:"result"`
-		} `json:"data"`
-	}
-
-	err = req.ResponUnmarshal(json.Unmarshal, &j)
-	if err != nil {
-		return
-	} else if j.Code != 0 {
-		err = errors.New(j.Message)
-		return
-	}
-
-	for i := 0; i < len(j.Data.Result); i += 1 {
-		uname := strings.ReplaceAll(j.Data.Result[i].Uname, `<em class="keyword">`, ``)
-		uname = strings.ReplaceAll(uname, `</em>`, ``)
-		res = append(res, struct {
-			Roomid  int
-			Uname   string
-			Is_live bool
-		}{
-			Roomid:  j.Data.Result[i].Roomid,
-			Uname:   uname,
-			Is_live: j.Data.Result[i].IsLive == 1,
-		})
-	}
-
-	err = req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
-		return nil
-	})
-	return
-}
-
 // GetHisDanmu implements biliApiInter.
 func (t *biliApi) GetHisDanmu(Roomid int) (err error, res []string) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetHisDanmu", reqf.Rval{
 		Url: "https://api.live.bilibili.com/xlive/web-room/v1/dM/gethistory?roomid=" + strconv.Itoa(Roomid),
 		Header: map[string]string{
 			`Referer`: "https://live.bilibili.com/" + strconv.Itoa(Roomid),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -488,148 +490,93 @@ func (t *biliApi) GetHisDanmu(Roomid int) (err error, res []string) {
 	}
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
 }
 
-// IsConnected implements biliApiInter.
-func (t *biliApi) IsConnected() (err error) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	return req.Reqf(reqf.Rval{
-		Url:                "https://www.bilibili.com",
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		JustResponseCode:   true,
-	})
-}
-
-// GetFollowing implements biliApiInter.
-func (t *biliApi) GetFollowing() (err error, res []struct {
-	Roomid     int
-	Uname      string
-	Title      string
-	LiveStatus int
-}) {
+// SendDanmu implements biliApiInter.
+func (t *biliApi) SendDanmu(Roomid int, msg string) (err error) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
 	}
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	for pageNum := 1; true; pageNum += 1 {
-		err = req.Reqf(reqf.Rval{
-			Url: `https://api.live.bilibili.com/xlive/web-ucenter/user/following?page=` + strconv.Itoa(pageNum) + `&page_size=10`,
-			Header: map[string]string{
-				`Host`:            `api.live.bilibili.com`,
-				`User-Agent`:      UA,
-				`Accept`:          `application/json, text/plain, */*`,
-				`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-				`Accept-Encoding`: `gzip, deflate, br`,
-				`Origin`:          `https://t.bilibili.com`,
-				`Connection`:      `keep-alive`,
-				`Pragma`:          `no-cache`,
-				`Cache-Control`:   `no-cache`,
-				`Referer`:         `https://t.bilibili.com/pages/nav/index_new`,
-				`Cookie`:          t.GetCookiesS(),
-			},
-			Proxy:              t.proxy,
-			DisableSystemProxy: t.disableSystemProxy,
-			Timeout:            3 * 1000,
-			Retry:              2,
-		})
-		if err != nil {
-			return
-		}
-		var j struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-			TTL     int    `json:"ttl"`
-			Data    struct {
-				TotalPage int `json:"totalPage"`
-				List      []struct {
-					Roomid     int    `json:"roomid"`
-					Uname      string `json:"uname"`
-					Title      string `json:"title"`
-					LiveStatus int    `json:"live_status"`
-				} `json:"list"`
-			} `json:"data"`
-		}
 
-		req.ResponUnmarshal(json.Unmarshal, &j)
-		if err != nil {
-			return
-		} else if j.Code != 0 {
-			err = errors.New(j.Message)
-			return
-		}
-
-		for _, item := range j.Data.List {
-			if item.LiveStatus == 0 {
-				break
-			} else {
-				res = append(res, struct {
-					Roomid     int
-					Uname      string
-					Title      string
-					LiveStatus int
-				}(item))
-			}
-		}
+	e, csrf := t.GetCookie(`bili_jct`)
+	if e != nil {
+		return ErrNeedLogin
+	}
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "SendDanmu", reqf.Rval{
+		Url: "https://api.live.bilibili.com/msg/send",
+		PostStr: fmt.Sprintf("bubble=0&msg=%s&color=16777215&mode=1&room_type=0&jumpfrom=0&reply_mid=0&reply_attr=0&replay_dmid=&statistics=%s&fontsize=25&rnd=%d&roomid=%d&csrf=%s&csrf_token=%s",
+			url.QueryEscape(msg), url.QueryEscape(`{"appId":100,"platform":5}`), time.Now().Unix(), Roomid, csrf, csrf),
+		Header: map[string]string{
+			`Content-Type`: `application/x-www-form-urlencoded`,
+			`Referer`:      "https://live.bilibili.com/" + strconv.Itoa(Roomid),
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
 
-		if pageNum*10 > j.Data.TotalPage {
-			break
-		}
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
 
-		time.Sleep(time.Second)
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
 	}
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
 }
 
+// IsConnected implements biliApiInter.
+func (t *biliApi) IsConnected() (err error) {
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	return t.do(c, req, "IsConnected", reqf.Rval{
+		Url:              "https://www.bilibili.com",
+		Timeout:          10 * 1000,
+		JustResponseCode: true,
+	})
+}
+
 // getOnlineGoldRank implements biliApiInter.
 func (t *biliApi) QueryContributionRank(upUid int, roomid int) (err error, OnlineNum int) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
 	// api queryContributionRank
 	{
 		query := fmt.Sprintf("ruid=%d&room_id=%d&page=1&page_size=100&type=online_rank&switch=contribution_rank&platform=web&web_location=444.8", upUid, roomid)
 
-		if e, v := t.GetNav(); e != nil {
-			err = e
-			return
-		} else if e, queryE := t.Wbi(query, v.WbiImg); e != nil {
+		if e, queryE := t.wbiSign(query); e != nil {
 			err = e
 			return
 		} else {
 			query = queryE
 		}
 
-		err = req.Reqf(reqf.Rval{
-			Url: "https://api.live.bilibili.com/xlive/general-interface/v1/rank/queryContributionRank?" + query,
-			Header: map[string]string{
-				`Host`:            `api.live.bilibili.com`,
-				`User-Agent`:      UA,
-				`Accept`:          `application/json, text/plain, */*`,
-				`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-				`Accept-Encoding`: `gzip, deflate, br`,
-				`Origin`:          `https://live.bilibili.com`,
-				`Connection`:      `keep-alive`,
-				`Pragma`:          `no-cache`,
-				`Cache-Control`:   `no-cache`,
-				`Cookie`:          t.GetCookiesS(),
-			},
-			Proxy:              t.proxy,
-			DisableSystemProxy: t.disableSystemProxy,
-			Timeout:            3 * 1000,
+		err = t.do(c, req, "QueryContributionRank", reqf.Rval{
+			Url:     "https://api.live.bilibili.com/xlive/general-interface/v1/rank/queryContributionRank?" + query,
+			Header:  map[string]string{},
+			Timeout: 3 * 1000,
 		})
 		if err == nil {
 			var j struct {
@@ -645,6 +592,9 @@ func (t *biliApi) QueryContributionRank(upUid int, roomid int) (err error, Onlin
 			if err != nil {
 				return
 			} else if j.Code != 0 {
+				if j.Code == -352 {
+					t.wbi.invalidate()
+				}
 				err = errors.New(j.Message)
 				return
 			}
@@ -652,7 +602,7 @@ func (t *biliApi) QueryContributionRank(upUid int, roomid int) (err error, Onlin
 			OnlineNum = j.Data.Count
 
 			req.Response(func(r *http.Response) error {
-				t.SetCookies(r.Cookies())
+				t.setRespCookies(r)
 				return nil
 			})
 		}
@@ -662,28 +612,16 @@ func (t *biliApi) QueryContributionRank(upUid int, roomid int) (err error, Onlin
 
 // getOnlineGoldRank implements biliApiInter.
 func (t *biliApi) GetOnlineGoldRank(upUid int, roomid int) (err error, OnlineNum int) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 	// api getOnlineGoldRank
 	{
 
-		err = req.Reqf(reqf.Rval{
-			Url: fmt.Sprintf("https://api.live.bilibili.com/xlive/general-interface/v1/rank/getOnlineGoldRank?ruid=%d&roomId=%d&page=1&pageSize=10", upUid, roomid),
-			Header: map[string]string{
-				`Host`:            `api.live.bilibili.com`,
-				`User-Agent`:      UA,
-				`Accept`:          `application/json, text/plain, */*`,
-				`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-				`Accept-Encoding`: `gzip, deflate, br`,
-				`Origin`:          `https://live.bilibili.com`,
-				`Connection`:      `keep-alive`,
-				`Pragma`:          `no-cache`,
-				`Cache-Control`:   `no-cache`,
-				`Cookie`:          t.GetCookiesS(),
-			},
-			Proxy:              t.proxy,
-			DisableSystemProxy: t.disableSystemProxy,
-			Timeout:            3 * 1000,
+		err = t.do(c, req, "GetOnlineGoldRank", reqf.Rval{
+			Url:     fmt.Sprintf("https://api.live.bilibili.com/xlive/general-interface/v1/rank/getOnlineGoldRank?ruid=%d&roomId=%d&page=1&pageSize=10", upUid, roomid),
+			Header:  map[string]string{},
+			Timeout: 3 * 1000,
 		})
 		if err == nil {
 			var j struct {
@@ -706,7 +644,7 @@ func (t *biliApi) GetOnlineGoldRank(upUid int, roomid int) (err error, OnlineNum
 			OnlineNum = j.Data.OnlineNum
 
 			req.Response(func(r *http.Response) error {
-				t.SetCookies(r.Cookies())
+				t.setRespCookies(r)
 				return nil
 			})
 			return
@@ -718,6 +656,7 @@ func (t *biliApi) GetOnlineGoldRank(upUid int, roomid int) (err error, OnlineNum
 
 // RoomEntryAction implements biliApiInter.
 func (t *biliApi) RoomEntryAction(Roomid int) (err error) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
@@ -728,29 +667,16 @@ func (t *biliApi) RoomEntryAction(Roomid int) (err error) {
 		csrf = t
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "RoomEntryAction", reqf.Rval{
 		Url:     `https://api.live.bilibili.com/xlive/web-room/v1/index/roomEntryAction`,
 		PostStr: fmt.Sprintf("room_id=%d&platform=pc&csrf_token=%s&csrf=%s&visit_id=", Roomid, csrf, csrf),
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
-			`Cookie`:          t.GetCookiesS(),
+			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -769,86 +695,7 @@ func (t *biliApi) RoomEntryAction(Roomid int) (err error) {
 	}
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
-		return nil
-	})
-	return
-}
-
-// GetHisStream implements biliApiInter.
-func (t *biliApi) GetHisStream() (err error, res []struct {
-	Uname      string
-	Title      string
-	Roomid     int
-	LiveStatus int
-}) {
-	if !t.IsLogin() {
-		err = ErrNeedLogin
-		return
-	}
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
-		Url: `https://api.bilibili.com/x/web-interface/history/cursor?type=live&ps=10`,
-		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://t.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         `https://t.bilibili.com/pages/nav/index_new`,
-			`Cookie`:          t.GetCookiesS(),
-		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
-	})
-	if err != nil {
-		return
-	}
-	var j struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
-		TTL     int    `json:"ttl"`
-		Data    struct {
-			List []struct {
-				Title      string `json:"title"`
-				AuthorName string `json:"author_name"`
-				Kid        int    `json:"kid"`
-				LiveStatus int    `json:"live_status"`
-			} `json:"list"`
-		} `json:"data"`
-	}
-
-	req.ResponUnmarshal(json.Unmarshal, &j)
-	if err != nil {
-		return
-	} else if j.Code != 0 {
-		err = errors.New(j.Message)
-		return
-	}
-
-	for _, item := range j.Data.List {
-		res = append(res, struct {
-			Uname      string
-			Title      string
-			Roomid     int
-			LiveStatus int
-		}{
-			Uname:      item.AuthorName,
-			Title:      item.Title,
-			Roomid:     item.Kid,
-			LiveStatus: item.LiveStatus,
-		})
-	}
-
-	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -858,17 +705,37 @@ func (t *biliApi) GetHisStream() (err error, res []struct {
 func (t *biliApi) GetCookies() (cookies []*http.Cookie) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
-	return append(cookies, t.cookies...)
+	return t.cookies.cookies(nil, time.Now())
 }
 
+// GetCookiesS 返回所有未过期cookie
 func (t *biliApi) GetCookiesS() (cookies string) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
-	return reqf.Cookies_List_2_String(t.cookies)
+	return reqf.Cookies_List_2_String(t.cookies.cookies(nil, time.Now()))
+}
+
+// cookiesFor 返回可发往rawURL的cookie
+func (t *biliApi) cookiesFor(rawURL string) (cookies string) {
+	u, e := url.Parse(rawURL)
+	if e != nil {
+		return
+	}
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return reqf.Cookies_List_2_String(t.cookies.cookies(u, time.Now()))
+}
+
+// GetCookiesExpires implements biliApiInter.
+func (t *biliApi) GetCookiesExpires() (name string, expires time.Time) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.cookies.soonest(time.Now())
 }
 
 // Silver2coin implements biliApiInter.
 func (t *biliApi) Silver2coin() (err error, Message string) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
@@ -878,29 +745,17 @@ func (t *biliApi) Silver2coin() (err error, Message string) {
 	if e != nil {
 		return err, ""
 	}
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "Silver2coin", reqf.Rval{
 		Url:     `https://api.live.bilibili.com/xlive/revenue/v1/wallet/silver2coin`,
 		PostStr: url.PathEscape(fmt.Sprintf("csrf_token=%s&csrf=%s", csrf, csrf)),
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://link.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Content-Type`:    `application/x-www-form-urlencoded`,
-			`Referer`:         `https://link.bilibili.com/p/center/index`,
-			`Cookie`:          t.GetCookiesS(),
+			`Origin`:       `https://link.bilibili.com`,
+			`Content-Type`: `application/x-www-form-urlencoded`,
+			`Referer`:      `https://link.bilibili.com/p/center/index`,
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -919,7 +774,7 @@ func (t *biliApi) Silver2coin() (err error, Message string) {
 	}
 	Message = j.Message
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -927,32 +782,27 @@ func (t *biliApi) Silver2coin() (err error, Message string) {
 
 // GetWalletRule implements biliApiInter.
 func (t *biliApi) GetWalletRule() (err error, Silver2CoinPrice int) {
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &Silver2CoinPrice, `GetWalletRule`); hit {
+		return e, Silver2CoinPrice
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetWalletRule", reqf.Rval{
 		Url: `https://api.live.bilibili.com/xlive/revenue/v1/wallet/getRule`,
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://link.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         `https://link.bilibili.com/p/center/index`,
-			`Cookie`:          t.GetCookiesS(),
+			`Origin`:  `https://link.bilibili.com`,
+			`Referer`: `https://link.bilibili.com/p/center/index`,
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -976,7 +826,7 @@ func (t *biliApi) GetWalletRule() (err error, Silver2CoinPrice int) {
 
 	Silver2CoinPrice = j.Data.Silver2CoinPrice
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -987,31 +837,20 @@ func (t *biliApi) GetWalletStatus() (err error, res struct {
 	Silver          int
 	Silver2CoinLeft int
 }) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
 	}
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetWalletStatus", reqf.Rval{
 		Url: `https://api.live.bilibili.com/xlive/revenue/v1/wallet/getStatus`,
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://link.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         `https://link.bilibili.com/p/center/index`,
-			`Cookie`:          t.GetCookiesS(),
+			`Origin`:  `https://link.bilibili.com`,
+			`Referer`: `https://link.bilibili.com/p/center/index`,
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1040,7 +879,7 @@ func (t *biliApi) GetWalletStatus() (err error, res struct {
 	}(j.Data)
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1054,33 +893,21 @@ func (t *biliApi) GetBagList(Roomid int) (err error, res []struct {
 	Gift_num  int
 	Expire_at int
 }) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GetBagList", reqf.Rval{
 		Url: `https://api.live.bilibili.com/xlive/web-room/v1/gift/bag_list?t=` + strconv.Itoa(int(time.Now().UnixNano()/int64(time.Millisecond))) + `&room_id=` + strconv.Itoa(Roomid),
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         "https://live.bilibili.com/" + strconv.Itoa(Roomid),
-			`Cookie`:          t.GetCookiesS(),
+			`Referer`: "https://live.bilibili.com/" + strconv.Itoa(Roomid),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1115,7 +942,7 @@ func (t *biliApi) GetBagList(Roomid int) (err error, res []struct {
 		Expire_at int
 	}(j.Data.List)
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1123,32 +950,21 @@ func (t *biliApi) GetBagList(Roomid int) (err error, res []struct {
 
 // GetLiveBuvid implements biliApiInter.
 func (t *biliApi) GetLiveBuvid(Roomid int) (err error) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetLiveBuvid", reqf.Rval{
 		Url: fmt.Sprintf("https://api.live.bilibili.com/live/getRoomKanBanModel?roomid=%d", Roomid),
 		Header: map[string]string{
-			`Host`:                      `live.bilibili.com`,
-			`User-Agent`:                UA,
-			`Accept`:                    `text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8`,
-			`Accept-Language`:           `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`:           `gzip, deflate, br`,
-			`Connection`:                `keep-alive`,
-			`Cache-Control`:             `no-cache`,
-			`Referer`:                   "https://live.bilibili.com",
-			`DNT`:                       `1`,
-			`Upgrade-Insecure-Requests`: `1`,
+			`Referer`: "https://live.bilibili.com",
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
 	}
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1156,23 +972,19 @@ func (t *biliApi) GetLiveBuvid(Roomid int) (err error) {
 
 // GetOtherCookies implements biliApiInter.
 func (t *biliApi) GetOtherCookies() (err error) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
-		Url: `https://www.bilibili.com/`,
-		Header: map[string]string{
-			`Cookie`: t.GetCookiesS(),
-		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetOtherCookies", reqf.Rval{
+		Url:     `https://www.bilibili.com/`,
+		Header:  map[string]string{},
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
 	}
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1180,31 +992,19 @@ func (t *biliApi) GetOtherCookies() (err error) {
 
 // DoSign implements biliApiInter.
 func (t *biliApi) DoSign() (err error, HadSignDays int) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
 	}
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "DoSign", reqf.Rval{
 		Url: `https://api.live.bilibili.com/xlive/web-ucenter/v1/sign/DoSign`,
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         "https://live.bilibili.com/all",
-			`Cookie`:          t.GetCookiesS(),
+			`Referer`: "https://live.bilibili.com/all",
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1227,7 +1027,7 @@ func (t *biliApi) DoSign() (err error, HadSignDays int) {
 
 	HadSignDays = j.Data.HadSignDays
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1235,32 +1035,20 @@ func (t *biliApi) DoSign() (err error, HadSignDays int) {
 
 // GetWebGetSignInfo implements biliApiInter.
 func (t *biliApi) GetWebGetSignInfo() (err error, Status int) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetWebGetSignInfo", reqf.Rval{
 		Url: `https://api.live.bilibili.com/xlive/web-ucenter/v1/sign/WebGetSignInfo`,
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         "https://live.bilibili.com/all",
-			`Cookie`:          t.GetCookiesS(),
+			`Referer`: "https://live.bilibili.com/all",
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 
 	if err != nil {
@@ -1284,26 +1072,25 @@ func (t *biliApi) GetWebGetSignInfo() (err error, Status int) {
 	}
 	Status = j.Data.Status
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
 }
 
-// GetCookies implements biliApiInter.
+// GetCookie implements biliApiInter.
 func (t *biliApi) GetCookie(name string) (error, string) {
 	t.lock.RLock()
 	defer t.lock.RUnlock()
-	for i := 0; i < len(t.cookies); i++ {
-		if t.cookies[i].Name == name {
-			return nil, t.cookies[i].Value
-		}
+	if v, ok := t.cookies.get(name, time.Now()); ok {
+		return nil, v
 	}
 	return errors.New("cookie not found: " + name), ""
 }
 
 // SetFansMedal implements biliApiInter.
 func (t *biliApi) SetFansMedal(medalId int) (err error) {
+	c := t.config()
 	post_url := `https://api.live.bilibili.com/xlive/web-room/v1/fansMedal/take_off` //无牌，不佩戴牌子
 	post_str := ""
 
@@ -1316,20 +1103,15 @@ func (t *biliApi) SetFansMedal(medalId int) (err error) {
 		post_str = fmt.Sprintf("medal_id=%d&csrf_token=%s&csrf=%s", medalId, csrf, csrf)
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "SetFansMedal", reqf.Rval{
 		Url:     post_url,
 		PostStr: post_str,
 		Header: map[string]string{
-			`Cookie`:       t.GetCookiesS(),
 			`Content-Type`: `application/x-www-form-urlencoded; charset=UTF-8`,
-			`Referer`:      `https://passport.bilibili.com/login`,
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -1350,7 +1132,7 @@ func (t *biliApi) SetFansMedal(medalId int) (err error) {
 	}
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1365,155 +1147,32 @@ func (t *biliApi) GetFansMedal(RoomID, TargetID int) (err error, res []struct {
 	RoomID       int
 	LivingStatus int
 }) {
-	if !t.IsLogin() {
-		err = ErrNeedLogin
-		return
-	}
-	//获取牌子列表
-	r := t.pool.Get()
-	defer t.pool.Put(r)
-
-	for pageNum := 1; true; pageNum += 1 {
-		url := fmt.Sprintf("https://api.live.bilibili.com/xlive/app-ucenter/v1/fansMedal/panel?page=%d&page_size=10", pageNum)
-		if RoomID != 0 {
-			url += fmt.Sprintf("&room_id=%d", RoomID)
-		}
-		if TargetID != 0 {
-			url += fmt.Sprintf("&target_id=%d", TargetID)
-		}
-
-		err = r.Reqf(reqf.Rval{
-			Url: url,
-			Header: map[string]string{
-				`Cookie`:  t.GetCookiesS(),
-				`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", RoomID),
-			},
-			Proxy:              t.proxy,
-			DisableSystemProxy: t.disableSystemProxy,
-			Timeout:            10 * 1000,
-			Retry:              2,
-		})
-		if err != nil {
-			return
-		}
-
-		var j struct {
-			Code    int    `json:"code"`
-			Message string `json:"message"`
-			TTL     int    `json:"ttl"`
-			Data    struct {
-				List []struct {
-					Medal struct {
-						TodayFeed int `json:"today_feed"`
-						TargetID  int `json:"target_id"`
-						MedalID   int `json:"medal_id"`
-						IsLighted int `json:"is_lighted"`
-					} `json:"medal"`
-					AnchorInfo struct {
-						NickName string `json:"nick_name"`
-					} `json:"anchor_info"`
-					RoomInfo struct {
-						RoomID       int `json:"room_id"`
-						LivingStatus int `json:"living_status"`
-					} `json:"room_info"`
-				} `json:"list"`
-				SpecialList []struct {
-					Medal struct {
-						TodayFeed int `json:"today_feed"`
-						TargetID  int `json:"target_id"`
-						MedalID   int `json:"medal_id"`
-						IsLighted int `json:"is_lighted"`
-					} `json:"medal"`
-					AnchorInfo struct {
-						NickName string `json:"nick_name"`
-					} `json:"anchor_info"`
-					RoomInfo struct {
-						RoomID       int `json:"room_id"`
-						LivingStatus int `json:"living_status"`
-					} `json:"room_info"`
-				} `json:"special_list"`
-				PageInfo struct {
-					CurrentPage int `json:"current_page"`
-					TotalPage   int `json:"total_page"`
-				} `json:"page_info"`
-			} `json:"data"`
-		}
-
-		err = r.ResponUnmarshal(json.Unmarshal, &j)
-		if err != nil {
-			return
-		} else if j.Code != 0 {
-			err = errors.New(j.Message)
-			return
+	filter := FansMedalFilter{RoomID: RoomID}
+	if TargetID != 0 {
+		filter.TargetIDs = []int{TargetID}
+	}
+	err = t.RangeFansMedal(filter, func(m FansMedal) bool {
+		var isLighted int
+		if m.IsLighted {
+			isLighted = 1
 		}
-
-		r.Response(func(r *http.Response) error {
-			t.SetCookies(r.Cookies())
-			return nil
+		res = append(res, struct {
+			TodayFeed    int
+			TargetID     int
+			IsLighted    int
+			MedalID      int
+			RoomID       int
+			LivingStatus int
+		}{
+			TodayFeed:    m.TodayFeed,
+			TargetID:     m.TargetID,
+			IsLighted:    isLighted,
+			MedalID:      m.MedalID,
+			RoomID:       m.RoomID,
+			LivingStatus: m.LivingStatus,
 		})
-		for i := 0; i < len(j.Data.SpecialList); i++ {
-			li := j.Data.SpecialList[i]
-			if RoomID != 0 && li.RoomInfo.RoomID != RoomID {
-				continue
-			}
-			if TargetID != 0 && TargetID != li.Medal.TargetID {
-				continue
-			}
-			res = append(res, struct {
-				TodayFeed    int
-				TargetID     int
-				IsLighted    int
-				MedalID      int
-				RoomID       int
-				LivingStatus int
-			}{
-				TodayFeed:    li.Medal.TodayFeed,
-				TargetID:     li.Medal.TargetID,
-				IsLighted:    li.Medal.IsLighted,
-				MedalID:      li.Medal.MedalID,
-				RoomID:       li.RoomInfo.RoomID,
-				LivingStatus: li.RoomInfo.LivingStatus,
-			})
-			if RoomID != 0 || TargetID != 0 {
-				return
-			}
-		}
-
-		for i := 0; i < len(j.Data.List); i++ {
-			li := j.Data.List[i]
-			if RoomID != 0 && li.RoomInfo.RoomID != RoomID {
-				continue
-			}
-			if TargetID != 0 && TargetID != li.Medal.TargetID {
-				continue
-			}
-			res = append(res, struct {
-				TodayFeed    int
-				TargetID     int
-				IsLighted    int
-				MedalID      int
-				RoomID       int
-				LivingStatus int
-			}{
-				TodayFeed:    li.Medal.TodayFeed,
-				TargetID:     li.Medal.TargetID,
-				IsLighted:    li.Medal.IsLighted,
-				MedalID:      li.Medal.MedalID,
-				RoomID:       li.RoomInfo.RoomID,
-				LivingStatus: li.RoomInfo.LivingStatus,
-			})
-			if RoomID != 0 || TargetID != 0 {
-				return
-			}
-		}
-
-		if j.Data.PageInfo.CurrentPage == j.Data.PageInfo.TotalPage {
-			break
-		}
-
-		time.Sleep(time.Second)
-	}
-
+		return RoomID == 0 && TargetID == 0
+	})
 	return
 }
 
@@ -1523,6 +1182,7 @@ func (t *biliApi) GetWearedMedal(uid, upUid int) (err error, res struct {
 	RoomID        int
 	TargetID      int
 }) {
+	c := t.config()
 	if !t.IsLogin() {
 		err = ErrNeedLogin
 		return
@@ -1533,18 +1193,13 @@ func (t *biliApi) GetWearedMedal(uid, upUid int) (err error, res struct {
 		csrf = t
 	}
 
-	r := t.pool.Get()
-	defer t.pool.Put(r)
-	err = r.Reqf(reqf.Rval{
+	r := c.pool.Get()
+	defer c.pool.Put(r)
+	err = t.do(c, r, "GetWearedMedal", reqf.Rval{
 		Url:     `https://api.live.bilibili.com/live_user/v1/UserInfo/get_weared_medal`,
 		PostStr: fmt.Sprintf("source=1&uid=%d&target_id=%d&csrf_token=%s&csrf=%s&visit_id=", uid, upUid, csrf, csrf),
-		Header: map[string]string{
-			`Cookie`: t.GetCookiesS(),
-		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+		Header:  map[string]string{},
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -1573,7 +1228,7 @@ func (t *biliApi) GetWearedMedal(uid, upUid int) (err error, res struct {
 	}
 
 	r.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	switch j.Data.(type) {
@@ -1601,8 +1256,7 @@ func (t *biliApi) Wbi(query string, WbiImg struct {
 	SubURL string
 }) (err error, queryEnc string) {
 	if query != "" {
-		wrid, wts := getWridWts(query, WbiImg.ImgURL, WbiImg.SubURL)
-		queryEnc = query + "&w_rid=" + wrid + "&wts=" + wts
+		queryEnc, _, _ = getWridWts(query, WbiImg.ImgURL, WbiImg.SubURL)
 	}
 	return
 }
@@ -1615,33 +1269,22 @@ func (t *biliApi) GetNav() (err error, res struct {
 		SubURL string
 	}
 }) {
-	vr, loaded, f := t.cache.LoadOrStore(`webImg`)
-	if loaded {
-		res = *vr
-		return
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &res, `GetNav`); hit {
+		return e, res
+	} else if done != nil {
+		defer func() { done(err) }()
 	}
 
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetNav", reqf.Rval{
 		Url: `https://api.bilibili.com/x/web-interface/nav`,
 		Header: map[string]string{
-			`Host`:            `api.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://t.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         `https://t.bilibili.com/pages/nav/index_new`,
-			`Cookie`:          t.GetCookiesS(),
+			`Origin`:  `https://t.bilibili.com`,
+			`Referer`: `https://t.bilibili.com/pages/nav/index_new`,
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1660,30 +1303,41 @@ func (t *biliApi) GetNav() (err error, res struct {
 		} `json:"data"`
 	}
 
-	req.ResponUnmarshal(json.Unmarshal, &j)
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
 	if err != nil {
 		return
+	} else if j.Code != 0 && j.Code != -101 {
+		// -101为未登录，其余(如-352)无法判断登录状态
+		err = errors.New(j.Message)
+		return
 	} else {
 		res.IsLogin = j.Data.IsLogin
 		res.WbiImg.ImgURL = j.Data.WbiImg.ImgURL
 		res.WbiImg.SubURL = j.Data.WbiImg.SubURL
+		t.wbi.set(res.WbiImg.ImgURL, res.WbiImg.SubURL, time.Now())
 	}
 
-	f(&res, time.Minute)
-
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies(), !res.IsLogin)
+		t.setRespCookies(r)
 		return nil
 	})
+	// 登录已失效，仅移除登录相关的cookie，保留设备指纹、bili_ticket
+	if !res.IsLogin {
+		t.delCookies(loginCookies...)
+	}
 
-	err = t.GenWebTicket()
-
+	// bili_ticket更新失败不影响nav的结果，仅记录
+	if e := t.ensureWebTicket(); e != nil {
+		c.logError(`GetNav`, e)
+	}
 	return
 }
 
+// GenWebTicket implements biliApiInter.
 func (t *biliApi) GenWebTicket() (err error) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
 	ts := fmt.Sprintf("%d", time.Now().Unix())
 
@@ -1701,26 +1355,14 @@ func (t *biliApi) GenWebTicket() (err error) {
 
 	query := fmt.Sprintf("key_id=ec02&hexsign=%s&context[ts]=%s&csrf=%s", fmt.Sprintf("%x", mac.Sum(nil)[:32]), ts, csrf)
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GenWebTicket", reqf.Rval{
 		Method: "POST",
 		Url:    `https://api.bilibili.com/bapis/bilibili.api.ticket.v1.Ticket/GenWebTicket?` + query,
 		Header: map[string]string{
-			`Host`:            `api.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://t.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         `https://t.bilibili.com/pages/nav/index_new`,
-			`Cookie`:          t.GetCookiesS(),
+			`Origin`:  `https://t.bilibili.com`,
+			`Referer`: `https://t.bilibili.com/pages/nav/index_new`,
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1743,44 +1385,78 @@ func (t *biliApi) GenWebTicket() (err error) {
 		TTL int `json:"ttl"`
 	}
 
-	req.ResponUnmarshal(json.Unmarshal, &j)
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
 	if err != nil {
 		return
-	} else {
-		t.SetCookies([]*http.Cookie{
-			{
-				Name:  "bili_ticket",
-				Value: j.Data.Ticket,
-			},
-		})
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	} else if j.Data.Ticket == `` {
+		err = errors.New(`Data.Ticket == ""`)
+		return
+	}
+
+	createdAt := time.Unix(int64(j.Data.CreatedAt), 0)
+	if j.Data.CreatedAt == 0 {
+		createdAt = time.Now()
+	}
+	ttl := time.Duration(j.Data.TTL) * time.Second
+	if ttl <= 0 {
+		ttl = webTicketTTL
 	}
+	expires := createdAt.Add(ttl)
+
+	t.wbi.set(j.Data.Nav.Img, j.Data.Nav.Sub, time.Now())
+	t.SetCookies([]*http.Cookie{
+		{
+			Name:    "bili_ticket",
+			Value:   j.Data.Ticket,
+			Path:    "/",
+			Expires: expires,
+		},
+		{
+			Name:    "bili_ticket_expires",
+			Value:   strconv.FormatInt(expires.Unix(), 10),
+			Path:    "/",
+			Expires: expires,
+		},
+	})
 	return
 }
 
+// ensureWebTicket bili_ticket不存在或将过期时重新生成
+func (t *biliApi) ensureWebTicket() (err error) {
+	t.ticketLock.Lock()
+	defer t.ticketLock.Unlock()
+
+	t.lock.RLock()
+	_, expires, ok := t.cookies.lookup(`bili_ticket`, time.Now())
+	t.lock.RUnlock()
+
+	if ok && !expires.IsZero() && time.Until(expires) > webTicketRefreshBefore {
+		return
+	}
+	return t.GenWebTicket()
+}
+
 // GetGuardNum implements biliApiInter.
 func (t *biliApi) GetGuardNum(upUid int, roomid int) (err error, GuardNum int) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &GuardNum, `GetGuardNum`, upUid, roomid); hit {
+		return e, GuardNum
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
 
-	err = req.Reqf(reqf.Rval{
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+
+	err = t.do(c, req, "GetGuardNum", reqf.Rval{
 		Url: fmt.Sprintf(`https://api.live.bilibili.com/xlive/app-room/v2/guardTab/topList?roomid=%d&page=1&ruid=%d&page_size=29`, roomid, upUid),
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         fmt.Sprintf("https://live.bilibili.com/%d", roomid),
-			`Cookie`:          t.GetCookiesS(),
+			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", roomid),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1809,7 +1485,7 @@ func (t *biliApi) GetGuardNum(upUid int, roomid int) (err error, GuardNum int) {
 	GuardNum = j.Data.Info.Num
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1817,28 +1493,22 @@ func (t *biliApi) GetGuardNum(upUid int, roomid int) (err error, GuardNum int) {
 
 // GetPopularAnchorRank implements biliApiInter.
 func (t *biliApi) GetPopularAnchorRank(uid int, upUid int, roomid int) (err error, note string) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &note, `GetPopularAnchorRank`, uid, upUid, roomid); hit {
+		return e, note
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GetPopularAnchorRank", reqf.Rval{
 		Url: fmt.Sprintf(`https://api.live.bilibili.com/xlive/general-interface/v1/rank/getPopularAnchorRank?uid=%d&ruid=%d&clientType=2`, uid, upUid),
 		Header: map[string]string{
-			`Host`:            `api.live.bilibili.com`,
-			`User-Agent`:      UA,
-			`Accept`:          `application/json, text/plain, */*`,
-			`Accept-Language`: `zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2`,
-			`Accept-Encoding`: `gzip, deflate, br`,
-			`Origin`:          `https://live.bilibili.com`,
-			`Connection`:      `keep-alive`,
-			`Pragma`:          `no-cache`,
-			`Cache-Control`:   `no-cache`,
-			`Referer`:         fmt.Sprintf("https://live.bilibili.com/%d", roomid),
-			`Cookie`:          t.GetCookiesS(),
+			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", roomid),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            3 * 1000,
-		Retry:              2,
+		Timeout: 3 * 1000,
 	})
 	if err != nil {
 		return
@@ -1872,7 +1542,7 @@ func (t *biliApi) GetPopularAnchorRank(uid int, upUid int, roomid int) (err erro
 	}
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1880,19 +1550,22 @@ func (t *biliApi) GetPopularAnchorRank(uid int, upUid int, roomid int) (err erro
 
 // getDanmuMedalAnchorInfo implements biliApiInter.
 func (t *biliApi) GetDanmuMedalAnchorInfo(Uid string, Roomid int) (err error, rface string) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &rface, `GetDanmuMedalAnchorInfo`, Uid, Roomid); hit {
+		return e, rface
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GetDanmuMedalAnchorInfo", reqf.Rval{
 		Url: "https://api.live.bilibili.com/xlive/web-room/v1/index/getDanmuMedalAnchorInfo?ruid=" + Uid,
 		Header: map[string]string{
 			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
-			`Cookie`:  t.GetCookiesS(),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -1917,7 +1590,7 @@ func (t *biliApi) GetDanmuMedalAnchorInfo(Uid string, Roomid int) (err error, rf
 	rface = j.Data.Rface + `@58w_58h`
 
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -1928,30 +1601,25 @@ func (t *biliApi) GetDanmuInfo(Roomid int) (err error, res struct {
 	Token string
 	WSURL []string
 }) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
 	query := fmt.Sprintf("type=0&id=%d", Roomid)
 
-	if e, v := t.GetNav(); e != nil {
-		err = e
-		return
-	} else if e, queryE := t.Wbi(query, v.WbiImg); e != nil {
+	if e, queryE := t.wbiSign(query); e != nil {
 		err = e
 		return
 	} else {
 		query = queryE
 	}
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GetDanmuInfo", reqf.Rval{
 		Url: "https://api.live.bilibili.com/xlive/web-room/v1/index/getDanmuInfo?" + query,
 		Header: map[string]string{
 			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
-			`Cookie`:  t.GetCookiesS(),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -1981,6 +1649,9 @@ func (t *biliApi) GetDanmuInfo(Roomid int) (err error, res struct {
 	if err != nil {
 		return
 	} else if j.Code != 0 {
+		if j.Code == -352 {
+			t.wbi.invalidate()
+		}
 		err = errors.New(j.Message)
 		return
 	}
@@ -1998,7 +1669,7 @@ func (t *biliApi) GetDanmuInfo(Roomid int) (err error, res struct {
 	}
 	res.WSURL = tmp
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -2031,18 +1702,15 @@ func (t *biliApi) GetRoomPlayInfo(Roomid int, Qn int) (err error, res struct {
 		}
 	}
 }) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
-	err = req.Reqf(reqf.Rval{
+	c := t.config()
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetRoomPlayInfo", reqf.Rval{
 		Url: fmt.Sprintf("https://api.live.bilibili.com/xlive/web-room/v2/index/getRoomPlayInfo?protocol=0,1&format=0,1,2&codec=0,1,2&qn=%d&platform=web&ptype=8&dolby=5&panorama=1&room_id=%d", Qn, Roomid),
 		Header: map[string]string{
 			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
-			`Cookie`:  t.GetCookiesS(),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -2150,7 +1818,7 @@ func (t *biliApi) GetRoomPlayInfo(Roomid int, Qn int) (err error, res struct {
 		}
 	}(j.Data.PlayurlInfo.Playurl.Stream)
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -2158,34 +1826,104 @@ func (t *biliApi) GetRoomPlayInfo(Roomid int, Qn int) (err error, res struct {
 
 // SetCookies implements biliApiInter.
 func (t *biliApi) SetCookies(cookies []*http.Cookie, overwrite ...bool) {
+	t.setCookies(nil, cookies, overwrite...)
+}
+
+// setRespCookies 保存响应中的cookie，作用域为请求的host
+func (t *biliApi) setRespCookies(r *http.Response, overwrite ...bool) {
+	var u *url.URL
+	if r.Request != nil {
+		u = r.Request.URL
+	}
+	t.setCookies(u, r.Cookies(), overwrite...)
+}
+
+// setCookies 保存cookie，overwrite时先清空，设备指纹将在下次请求前重新生成
+func (t *biliApi) setCookies(u *url.URL, cookies []*http.Cookie, overwrite ...bool) {
+	t.updateCookies(func(now time.Time) (changed bool) {
+		if len(overwrite) > 0 && overwrite[0] {
+			changed = t.cookies.reset()
+			t.fpDone.Store(false)
+		}
+		return t.cookies.set(u, cookies, now) || changed
+	})
+}
+
+// delCookies 移除名为names的cookie
+func (t *biliApi) delCookies(names ...string) {
+	t.updateCookies(func(now time.Time) bool {
+		return t.cookies.del(names...)
+	})
+}
+
+// updateCookies 持有lock时以f修改cookie，有变化时清除响应缓存并调用cookie回调
+func (t *biliApi) updateCookies(f func(now time.Time) (changed bool)) {
 	t.lock.Lock()
-	defer t.lock.Unlock()
-	var someRenew bool
-	if len(overwrite) > 0 && overwrite[0] {
-		someRenew = true
-		t.cookies = t.cookies[:0]
-	}
-	for _, v := range cookies {
-		found := false
-		for i2, v2 := range t.cookies {
-			if found = v.Name == v2.Name; found {
-				someRenew = someRenew || v.Value != v2.Value
-				if v.Value == `` {
-					t.cookies = append(t.cookies[:i2], t.cookies[i2+1:]...)
-				} else {
-					v2.Value = v.Value
-				}
-				break
-			}
+	now := time.Now()
+	someRenew := t.cookies.purge(now)
+	someRenew = f(now) || someRenew
+	var (
+		current []*http.Cookie
+		seq     uint64
+	)
+	if someRenew {
+		t.cookiesSeq += 1
+		current, seq = t.cookies.cookies(nil, now), t.cookiesSeq
+	}
+	t.lock.Unlock()
+
+	if someRenew {
+		t.respCache.invalidate()
+		t.cookiesCb.deliver(seq, current, func() func([]*http.Cookie) {
+			return t.config().cookiesCallback
+		})
+	}
+}
+
+// cookiesDelivery 串行调用cookie回调，旧于已传递的cookie将被丢弃
+// 正在传递时，新的cookie由正在传递者在之后传递，因此回调中可再次设置cookie
+type cookiesDelivery struct {
+	lock       sync.Mutex
+	delivering bool
+	pending    []*http.Cookie
+	pendingSeq uint64
+	lastSeq    uint64
+}
+
+func (t *cookiesDelivery) deliver(seq uint64, cookies []*http.Cookie, cb func() func([]*http.Cookie)) {
+	t.lock.Lock()
+	if seq > t.pendingSeq && seq > t.lastSeq {
+		t.pending, t.pendingSeq = cookies, seq
+	}
+	if t.delivering {
+		t.lock.Unlock()
+		return
+	}
+	t.delivering = true
+	t.lock.Unlock()
+
+	// 回调panic时，后续仍可传递
+	finished := false
+	defer func() {
+		if !finished {
+			t.lock.Lock()
+			t.delivering = false
+			t.lock.Unlock()
 		}
-		if !found && v.Value != `` {
-			someRenew = true
-			t.cookies = append(t.cookies, v)
+	}()
+	for {
+		t.lock.Lock()
+		if t.pendingSeq <= t.lastSeq {
+			t.delivering, finished = false, true
+			t.lock.Unlock()
+			return
+		}
+		cookies := t.pending
+		t.lastSeq, t.pending = t.pendingSeq, nil
+		t.lock.Unlock()
+		if f := cb(); f != nil {
+			f(cookies)
 		}
-	}
-	if t.cookiesCallback != nil && someRenew {
-		t.cache.Delete(`webImg`)
-		t.cookiesCallback(t.cookies)
 	}
 }
 
@@ -2204,30 +1942,31 @@ func (t *biliApi) GetInfoByRoom(Roomid int) (err error, res struct {
 	Note          string
 	Locked        bool
 }) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &res, `GetInfoByRoom`, Roomid); hit {
+		return e, res
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
 	query := fmt.Sprintf("room_id=%d&web_location=444.8", Roomid)
 
-	if e, v := t.GetNav(); e != nil {
-		err = e
-		return
-	} else if e, queryE := t.Wbi(query, v.WbiImg); e != nil {
+	if e, queryE := t.wbiSign(query); e != nil {
 		err = e
 		return
 	} else {
 		query = queryE
 	}
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GetInfoByRoom", reqf.Rval{
 		Url: "https://api.live.bilibili.com/xlive/web-room/v1/index/getInfoByRoom?" + query,
 		Header: map[string]string{
 			`Referer`: fmt.Sprintf("https://live.bilibili.com/%d", Roomid),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -2627,6 +2366,9 @@ func (t *biliApi) GetInfoByRoom(Roomid int) (err error, res struct {
 		if err != nil {
 			return
 		} else if j.Code != 0 {
+			if j.Code == -352 {
+				t.wbi.invalidate()
+			}
 			err = errors.New(j.Message)
 			return
 		}
@@ -2662,14 +2404,16 @@ func (t *biliApi) GetInfoByRoom(Roomid int) (err error, res struct {
 		res.Locked = j.Data.RoomInfo.LockStatus == 1
 	}
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
 }
 
 func (t *biliApi) SetReqPool(pool *pool.Buf[reqf.Req]) {
-	t.pool = pool
+	t.setConfig(func(c *biliApiConf) {
+		c.pool = pool
+	})
 }
 
 // GetRoomBaseInfo implements biliApiInter.
@@ -2684,17 +2428,22 @@ func (t *biliApi) GetRoomBaseInfo(Roomid int) (err error, res struct {
 	Liveing       bool
 	RoomID        int
 }) {
-	req := t.pool.Get()
-	defer t.pool.Put(req)
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &res, `GetRoomBaseInfo`, Roomid); hit {
+		return e, res
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
 
-	err = req.Reqf(reqf.Rval{
+	err = t.do(c, req, "GetRoomBaseInfo", reqf.Rval{
 		Url: fmt.Sprintf("https://api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo?req_biz=link-center&room_ids=%d", Roomid),
 		Header: map[string]string{
 			`Referer`: "https://link.bilibili.com/p/center/index",
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
+		Timeout: 10 * 1000,
 	})
 	if err != nil {
 		return
@@ -2752,7 +2501,7 @@ func (t *biliApi) GetRoomBaseInfo(Roomid int) (err error, res struct {
 				//直播间标题
 				res.Title = data.Title
 				//直播开始时间
-				if ti, e := time.ParseInLocation(time.DateTime, data.LiveTime, t.location); e == nil && !ti.IsZero() {
+				if ti, e := time.ParseInLocation(time.DateTime, data.LiveTime, c.location); e == nil && !ti.IsZero() {
 					res.LiveStartTime = ti
 				}
 				//是否在直播
@@ -2766,7 +2515,7 @@ func (t *biliApi) GetRoomBaseInfo(Roomid int) (err error, res struct {
 		}
 	}
 	req.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
@@ -2775,14 +2524,12 @@ func (t *biliApi) GetRoomBaseInfo(Roomid int) (err error, res struct {
 // LoginQrPoll implements F.BiliApi.
 // test
 func (t *biliApi) LoginQrPoll(QrcodeKey string) (err error, code int) {
-	r := t.pool.Get()
-	defer t.pool.Put(r)
-	if e := r.Reqf(reqf.Rval{
-		Url:                `https://passport.bilibili.com/x/passport-login/web/qrcode/poll?qrcode_key=` + QrcodeKey + `&source=main-fe-header`,
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+	c := t.config()
+	r := c.pool.Get()
+	defer c.pool.Put(r)
+	if e := t.do(c, r, "LoginQrPoll", reqf.Rval{
+		Url:     `https://passport.bilibili.com/x/passport-login/web/qrcode/poll?qrcode_key=` + QrcodeKey + `&source=main-fe-header`,
+		Timeout: 10 * 1000,
 	}); e != nil {
 		err = e
 		return
@@ -2812,30 +2559,32 @@ func (t *biliApi) LoginQrPoll(QrcodeKey string) (err error, code int) {
 	}
 	code = res.Data.Code
 	r.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
 }
 
 func (t *biliApi) SetProxy(proxy string) {
-	t.proxy = proxy
+	t.setConfig(func(c *biliApiConf) {
+		c.proxy = proxy
+	})
 }
 
 func (t *biliApi) SetDisableSystemProxy(disableSystemProxy bool) {
-	t.disableSystemProxy = disableSystemProxy
+	t.setConfig(func(c *biliApiConf) {
+		c.disableSystemProxy = disableSystemProxy
+	})
 }
 
 // test
 func (t *biliApi) LoginQrCode() (err error, imgUrl string, QrcodeKey string) {
-	r := t.pool.Get()
-	defer t.pool.Put(r)
-	if e := r.Reqf(reqf.Rval{
-		Url:                `https://passport.bilibili.com/x/passport-login/web/qrcode/generate?source=main-fe-header`,
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
+	c := t.config()
+	r := c.pool.Get()
+	defer c.pool.Put(r)
+	if e := t.do(c, r, "LoginQrCode", reqf.Rval{
+		Url:     `https://passport.bilibili.com/x/passport-login/web/qrcode/generate?source=main-fe-header`,
+		Timeout: 10 * 1000,
 	}); e != nil {
 		err = e
 		return
@@ -2873,15 +2622,16 @@ func (t *biliApi) LoginQrCode() (err error, imgUrl string, QrcodeKey string) {
 		QrcodeKey = res.Data.QrcodeKey
 	}
 	r.Response(func(r *http.Response) error {
-		t.SetCookies(r.Cookies())
+		t.setRespCookies(r)
 		return nil
 	})
 	return
 }
 
 func (t *biliApi) Logout() error {
-	r := t.pool.Get()
-	defer t.pool.Put(r)
+	c := t.config()
+	r := c.pool.Get()
+	defer c.pool.Put(r)
 
 	csrf := ""
 	if e, t := t.GetCookie(`bili_jct`); e == nil {
@@ -2890,65 +2640,20 @@ func (t *biliApi) Logout() error {
 		return ErrNoLogin
 	}
 
-	if e := r.Reqf(reqf.Rval{
+	if e := t.do(c, r, "Logout", reqf.Rval{
 		Url: `https://passport.bilibili.com/login/exit/v2`,
 		Header: map[string]string{
 			`Referer`: `https://www.bilibili.com/`,
-			`Cookie`:  t.GetCookiesS(),
 		},
-		Proxy:              t.proxy,
-		DisableSystemProxy: t.disableSystemProxy,
-		Timeout:            10 * 1000,
-		Retry:              2,
-		PostStr:            fmt.Sprintf("biliCSRF=%s&gourl=https%3A%2F%2Fwww.bilibili.com%2F", csrf),
+		Timeout: 10 * 1000,
+		PostStr: fmt.Sprintf("biliCSRF=%s&gourl=https%%3A%%2F%%2Fwww.bilibili.com%%2F", csrf),
 	}); e != nil {
 		return e
 	} else {
 		r.Response(func(r *http.Response) error {
-			t.SetCookies(r.Cookies(), r.StatusCode == 200)
+			t.setRespCookies(r, r.StatusCode == 200)
 			return nil
 		})
 		return nil
 	}
 }
-
-// bilibili wrid wts 计算
-func getWridWts(query string, imgURL, subURL string, customWts ...string) (w_rid, wts string) {
-	wbi := imgURL[strings.LastIndex(imgURL, "/")+1:strings.LastIndex(imgURL, ".")] +
-		subURL[strings.LastIndex(subURL, "/")+1:strings.LastIndex(subURL, ".")]
-
-	code := []int{46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35, 27, 43, 5,
-		49, 33, 9, 42, 19, 29, 28, 14, 39, 12, 38, 41, 13, 37, 48, 7, 16, 24, 55,
-		40, 61, 26, 17, 0, 1, 60, 51, 30, 4, 22, 25, 54, 21, 56, 59, 6, 63, 57,
-		62, 11, 36, 20, 34, 44, 52}
-
-	s := []byte{}
-
-	for i := 0; i < len(code); i++ {
-		if code[i] < len(wbi) {
-			s = append(s, wbi[code[i]])
-			if len(s) >= 32 {
-				break
-			}
-		}
-	}
-
-	object := strings.Split(query, "&")
-
-	if len(customWts) == 0 {
-		wts = fmt.Sprintf("%d", time.Now().Unix())
-	} else {
-		wts = customWts[0]
-	}
-	object = append(object, "wts="+wts)
-
-	slices.Sort(object)
-
-	for i := 0; i < len(object); i++ {
-		object[i] = url.PathEscape(object[i])
-	}
-
-	w_rid = fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(object, "&")+string(s))))
-
-	return
-}
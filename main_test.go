@@ -1,8 +1,18 @@
 package biliApi
 
 import (
+	"bytes"
 	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	cmp "github.com/qydysky/part/component2"
 	pool "github.com/qydysky/part/pool"
@@ -37,6 +47,241 @@ func init() {
 	})
 }
 
+func TestConfigRace(t *testing.T) {
+	var n atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+
+	a := New()
+	a.SetProxy(s.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.SetProxy(s.URL)
+			a.SetDisableSystemProxy(true)
+			a.SetLocation(8 * 3600)
+			a.SetReqPool(newReqPool())
+			a.SetCookiesCallback(func(cookies []*http.Cookie) {})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = a.IsConnected()
+			_, _ = a.GetRoomBaseInfo(213)
+			a.SetCookies([]*http.Cookie{{Name: `a`, Value: time.Now().String()}})
+		}()
+	}
+	wg.Wait()
+	if n.Load() == 0 {
+		t.Fatal()
+	}
+}
+
+func TestCookiesCallbackReentrant(t *testing.T) {
+	a := New()
+	a.SetCookiesCallback(func(cookies []*http.Cookie) {
+		_ = a.GetCookies()
+		cookies[0].Value = `changed`
+	})
+
+	done := make(chan struct{})
+	go func() {
+		a.SetCookies([]*http.Cookie{{Name: `a`, Value: `1`}})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal(`deadlock`)
+	}
+	if e, v := a.GetCookie(`a`); e != nil || v != `1` {
+		t.Fatal(v)
+	}
+
+	// 回调中设置cookie，之后传递新的cookie
+	var got []string
+	b := New()
+	b.SetCookiesCallback(func(cookies []*http.Cookie) {
+		for _, c := range cookies {
+			got = append(got, c.Name+`=`+c.Value)
+		}
+		if len(cookies) == 1 {
+			b.SetCookies([]*http.Cookie{{Name: `b`, Value: `2`}})
+		}
+	})
+	b.SetCookies([]*http.Cookie{{Name: `a`, Value: `1`}})
+	if len(got) != 3 || got[0] != `a=1` {
+		t.Fatal(got)
+	}
+}
+
+func TestCookiesCallbackOrder(t *testing.T) {
+	a := New()
+	var (
+		inFlight atomic.Int32
+		last     string
+	)
+	a.SetCookiesCallback(func(cookies []*http.Cookie) {
+		if inFlight.Add(1) != 1 {
+			t.Error(`concurrent callback`)
+		}
+		time.Sleep(time.Millisecond)
+		for _, c := range cookies {
+			if c.Name == `a` {
+				last = c.Value
+			}
+		}
+		inFlight.Add(-1)
+	})
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.SetCookies([]*http.Cookie{{Name: `a`, Value: strconv.Itoa(i)}})
+		}()
+	}
+	wg.Wait()
+	if e, v := a.GetCookie(`a`); e != nil || v != last {
+		t.Fatal(v, last)
+	}
+}
+
+func TestEnsureWebTicket(t *testing.T) {
+	var n atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer s.Close()
+
+	a := newBiliApi(newReqPool())
+	a.SetProxy(s.URL)
+
+	a.SetCookies([]*http.Cookie{{Name: `bili_ticket`, Value: `1`, Expires: time.Now().Add(48 * time.Hour)}})
+	if e := a.ensureWebTicket(); e != nil || n.Load() != 0 {
+		t.Fatal(e)
+	}
+
+	a.SetCookies([]*http.Cookie{{Name: `bili_ticket`, Value: `2`, Expires: time.Now().Add(time.Minute)}})
+	if e := a.ensureWebTicket(); e == nil || n.Load() == 0 {
+		t.Fatal()
+	}
+	if e, v := a.GetCookie(`bili_ticket`); e != nil || v != `2` {
+		t.Fatal(v)
+	}
+}
+
+func TestEnsureWebTicketInNav(t *testing.T) {
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case `/x/web-interface/nav`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"isLogin":false,"wbi_img":{"img_url":"https://i0.hdslb.com/bfs/wbi/a.png","sub_url":"https://i0.hdslb.com/bfs/wbi/b.png"}}}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+	defer closef()
+	var buf bytes.Buffer
+	a.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	// bili_ticket获取失败时nav仍成功，错误记录于日志
+	if e, res := a.GetNav(); e != nil || res.WbiImg.ImgURL == `` {
+		t.Fatal(e, res)
+	}
+	if e, _ := a.wbiSign(`a=1`); e != nil {
+		t.Fatal(e)
+	}
+	if !strings.Contains(buf.String(), `level=WARN`) || !strings.Contains(buf.String(), `api=GetNav`) {
+		t.Fatal(buf.String())
+	}
+}
+
+// newTestApi 返回请求均发往本地h的实例
+func newTestApi(h http.HandlerFunc) (a *biliApi, closef func()) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == `/x/frontend/finger/spi` {
+			_, _ = w.Write([]byte(`{"code":0,"data":{"b_3":"b3","b_4":"b4"}}`))
+			return
+		}
+		h(w, r)
+	}))
+	a = newBiliApi(newReqPool())
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			if u, e := url.Parse(req.Url); e != nil {
+				return e
+			} else {
+				req.Url = s.URL + u.RequestURI()
+			}
+			return nil
+		},
+	})
+	return a, s.Close
+}
+
+func TestMiddleware(t *testing.T) {
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`X-Test`) != `1` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{"213":{"room_id":213,"uid":1,"title":"t"}}}}`))
+	})
+	defer closef()
+
+	var (
+		order []string
+		res   ApiRes
+	)
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			order = append(order, `before1`)
+			req.Header[`X-Test`] = `1`
+			return nil
+		},
+		After: func(req *ApiReq, r *ApiRes) {
+			order = append(order, `after1`)
+			if req.Api == `GetRoomBaseInfo` {
+				res = *r
+				res.Body = append([]byte{}, r.Body...)
+			}
+		},
+	})
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			order = append(order, `before2`)
+			return nil
+		},
+		After: func(req *ApiReq, r *ApiRes) {
+			order = append(order, `after2`)
+		},
+	})
+
+	order = order[:0]
+	if e, v := a.GetRoomBaseInfo(213); e != nil || v.Title != `t` {
+		t.Fatal(e, v)
+	}
+	if res.StatusCode != http.StatusOK || res.Err != nil || len(res.Body) == 0 || res.Duration <= 0 {
+		t.Fatal(res)
+	}
+	if len(order) < 4 || order[len(order)-4] != `before1` || order[len(order)-3] != `before2` || order[len(order)-2] != `after2` || order[len(order)-1] != `after1` {
+		t.Fatal(order)
+	}
+
+	stop := errors.New(`stop`)
+	a.InvalidateCache(`GetRoomBaseInfo`)
+	a.AddMiddleware(Middleware{Before: func(req *ApiReq) error { return stop }})
+	if e, _ := a.GetRoomBaseInfo(213); !errors.Is(e, stop) {
+		t.Fatal(e)
+	}
+}
+
 func TestGetInfoByRoom(t *testing.T) {
 	if err, _ := api.GetInfoByRoom(213); err != nil {
 		t.Fatal(err)
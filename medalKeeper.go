@@ -0,0 +1,211 @@
+package biliApi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrMedalKeeperConf = errors.New(`ErrMedalKeeperConf`)
+
+// MedalAction 点亮粉丝牌的操作
+type MedalAction int
+
+const (
+	MedalByLike  MedalAction = iota // 点赞
+	MedalByDanmu                    // 发送弹幕
+	MedalByGift                     // 赠送一个背包礼物
+)
+
+func (t MedalAction) String() string {
+	switch t {
+	case MedalByDanmu:
+		return `danmu`
+	case MedalByGift:
+		return `gift`
+	default:
+		return `like`
+	}
+}
+
+// MedalKeeperConf 粉丝牌点亮配置
+type MedalKeeperConf struct {
+	Actions     []MedalAction   // 对每个粉丝牌依次执行的操作，为空时仅点赞
+	MinFeed     int             // 已点亮但今日亲密度低于此值时也执行，<=0时仅处理未点亮的
+	LikeCount   int             // 点赞次数，<=0时为30
+	Danmu       string          // 弹幕内容，Actions含MedalByDanmu时必填
+	GiftID      int             // 赠送的背包礼物，为0时赠送最早过期的背包礼物
+	MaxPerMedal int             // 每个粉丝牌每日最多成功执行的操作数，<=0时不限
+	MaxGifts    int             // 每个账号每日最多成功赠送的礼物数，<=0时不限
+	Filter      FansMedalFilter // 仅处理符合条件的粉丝牌
+	Store       TaskStore       // 保存每日额度，可与TaskRunner共用，nil时保存在内存中
+}
+
+// MedalProgress 单个粉丝牌的处理结果
+type MedalProgress struct {
+	MedalID   int
+	RoomID    int
+	TargetID  int
+	TodayFeed int           // 处理前的今日亲密度
+	IsLighted bool          // 处理前是否点亮
+	Done      []MedalAction // 成功执行的操作
+	Skipped   string        // 未处理的原因
+	Err       error
+}
+
+// MedalKeeper 找出未点亮或今日亲密度低的粉丝牌并点亮，可在多个账号间共用
+type MedalKeeper struct {
+	conf MedalKeeperConf
+	lock sync.Mutex // 额度的读取、保存
+}
+
+func NewMedalKeeper(conf MedalKeeperConf) (err error, keeper *MedalKeeper) {
+	if len(conf.Actions) == 0 {
+		conf.Actions = []MedalAction{MedalByLike}
+	}
+	for _, action := range conf.Actions {
+		switch action {
+		case MedalByLike, MedalByGift:
+		case MedalByDanmu:
+			if conf.Danmu == `` {
+				return fmt.Errorf("%w: MedalByDanmu需要Danmu", ErrMedalKeeperConf), nil
+			}
+		default:
+			return fmt.Errorf("%w: 未知的操作%d", ErrMedalKeeperConf, action), nil
+		}
+	}
+	if conf.LikeCount <= 0 {
+		conf.LikeCount = 30
+	}
+	if conf.Store == nil {
+		conf.Store = NewMemTaskStore()
+	}
+	return nil, &MedalKeeper{conf: conf}
+}
+
+// take 占用uid账号today(按账号时区)的额度，达到上限时ok为false，操作失败时应调用refund归还
+func (t *MedalKeeper) take(uid int, today string, key string, max int) (err error, ok bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	e, state, _ := t.conf.Store.Load(uid, key)
+	if e != nil {
+		return e, false
+	}
+	if state.Day != today {
+		state = TaskState{Day: today}
+	}
+	if max > 0 && state.Count >= max {
+		return nil, false
+	}
+	state.Count += 1
+	state.LastRun = time.Now()
+	return t.conf.Store.Save(uid, key, state), true
+}
+
+// refund 归还take占用的额度
+func (t *MedalKeeper) refund(uid int, today string, key string) (err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	e, state, _ := t.conf.Store.Load(uid, key)
+	if e != nil || state.Day != today || state.Count <= 0 {
+		return e
+	}
+	state.Count -= 1
+	return t.conf.Store.Save(uid, key, state)
+}
+
+// Run 处理api账号的所有粉丝牌，每处理完一个调用progress，progress可为nil
+func (t *MedalKeeper) Run(api BiliApi, progress func(p MedalProgress)) (err error, res []MedalProgress) {
+	e, uid := Uid(api)
+	if e != nil {
+		return e, nil
+	}
+	e, medals := api.GetFansMedals(t.conf.Filter)
+	if e != nil {
+		return e, nil
+	}
+
+	today := time.Now().In(api.GetLocation()).Format(time.DateOnly)
+	for _, m := range medals {
+		p := MedalProgress{
+			MedalID:   m.MedalID,
+			RoomID:    m.RoomID,
+			TargetID:  m.TargetID,
+			TodayFeed: m.TodayFeed,
+			IsLighted: m.IsLighted,
+		}
+		switch {
+		case m.RoomID == 0:
+			p.Skipped = `无直播间`
+		case m.DayLimit > 0 && m.TodayFeed >= m.DayLimit:
+			p.Skipped = `达到今日亲密度上限`
+		case p.IsLighted && (t.conf.MinFeed <= 0 || m.TodayFeed >= t.conf.MinFeed):
+			p.Skipped = `已点亮`
+		default:
+			key := fmt.Sprintf("medalKeeper/medal/%d", m.MedalID)
+			for _, action := range t.conf.Actions {
+				if e, ok := t.take(uid, today, key, t.conf.MaxPerMedal); e != nil {
+					p.Err = errors.Join(p.Err, e)
+					break
+				} else if !ok {
+					p.Skipped = `达到每日上限`
+					break
+				}
+				if e := t.act(api, uid, today, m.RoomID, m.TargetID, action); e != nil {
+					p.Err = errors.Join(p.Err, fmt.Errorf("%s: %w", action, e), t.refund(uid, today, key))
+				} else {
+					p.Done = append(p.Done, action)
+				}
+			}
+		}
+		if p.Err != nil {
+			err = errors.Join(err, fmt.Errorf("%d: %w", p.RoomID, p.Err))
+		}
+		if progress != nil {
+			progress(p)
+		}
+		res = append(res, p)
+	}
+	return
+}
+
+func (t *MedalKeeper) act(api BiliApi, uid int, today string, roomid, upUid int, action MedalAction) error {
+	switch action {
+	case MedalByDanmu:
+		if t.conf.Danmu == `` {
+			return fmt.Errorf("%w: MedalByDanmu需要Danmu", ErrMedalKeeperConf)
+		}
+		return api.SendDanmu(roomid, t.conf.Danmu)
+	case MedalByGift:
+		e, list := api.GetBagList(roomid)
+		if e != nil {
+			return e
+		}
+		var bagID, giftID, expireAt int
+		for _, item := range list {
+			if item.Gift_num <= 0 || (t.conf.GiftID != 0 && item.Gift_id != t.conf.GiftID) {
+				continue
+			}
+			// 0为永久，最后考虑
+			if bagID == 0 || (item.Expire_at > 0 && (expireAt == 0 || item.Expire_at < expireAt)) {
+				bagID, giftID, expireAt = item.Bag_id, item.Gift_id, item.Expire_at
+			}
+		}
+		if bagID == 0 {
+			return errors.New(`背包中无可用礼物`)
+		}
+		const key = `medalKeeper/gift`
+		if e, ok := t.take(uid, today, key, t.conf.MaxGifts); e != nil {
+			return e
+		} else if !ok {
+			return errors.New(`达到每日礼物上限`)
+		}
+		if e, _ = api.SendBagGift(roomid, upUid, bagID, giftID, 1); e != nil {
+			return errors.Join(e, t.refund(uid, today, key))
+		}
+		return nil
+	default:
+		return api.LikeReport(t.conf.LikeCount, uid, roomid, upUid)
+	}
+}
@@ -0,0 +1,131 @@
+package biliApi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMedalKeeper(t *testing.T) {
+	var (
+		likes, danmus, gifts []string
+		giftFail             = true
+	)
+	h := func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.URL.Path {
+		case `/xlive/app-ucenter/v1/fansMedal/panel`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"list":[
+				{"medal":{"today_feed":0,"day_limit":1500,"target_id":2,"medal_id":10,"is_lighted":0},"room_info":{"room_id":213,"living_status":0}},
+				{"medal":{"today_feed":50,"day_limit":1500,"target_id":3,"medal_id":11,"is_lighted":1},"room_info":{"room_id":214,"living_status":1}},
+				{"medal":{"today_feed":200,"day_limit":1500,"target_id":4,"medal_id":12,"is_lighted":1},"room_info":{"room_id":215,"living_status":0}},
+				{"medal":{"today_feed":0,"day_limit":1500,"target_id":5,"medal_id":13,"is_lighted":0},"room_info":{"room_id":0,"living_status":0}},
+				{"medal":{"today_feed":1500,"day_limit":1500,"target_id":6,"medal_id":14,"is_lighted":0},"room_info":{"room_id":216,"living_status":0}}
+			],"page_info":{"current_page":1,"total_page":1}}}`))
+		case `/xlive/app-ucenter/v1/like_info_v3/like/likeReportV3`:
+			likes = append(likes, r.PostForm.Get(`room_id`)+`/`+r.PostForm.Get(`click_time`))
+			_, _ = w.Write([]byte(`{"code":0}`))
+		case `/msg/send`:
+			danmus = append(danmus, r.PostForm.Get(`roomid`)+`/`+r.PostForm.Get(`msg`))
+			_, _ = w.Write([]byte(`{"code":0}`))
+		case `/xlive/web-room/v1/gift/bag_list`:
+			now := time.Now().Unix()
+			fmt.Fprintf(w, `{"code":0,"data":{"list":[
+				{"bag_id":3,"gift_id":3,"gift_name":"B坷垃","gift_num":1,"expire_at":0},
+				{"bag_id":2,"gift_id":1,"gift_name":"辣条","gift_num":5,"expire_at":%d},
+				{"bag_id":1,"gift_id":30607,"gift_name":"小心心","gift_num":3,"expire_at":%d}
+			]}}`, now+10*86400, now+3600)
+		case `/xlive/revenue/v1/gift/sendBag`:
+			if giftFail {
+				giftFail = false
+				_, _ = w.Write([]byte(`{"code":200013,"message":"赠送失败"}`))
+				return
+			}
+			gifts = append(gifts, r.PostForm.Get(`biz_id`)+`/`+r.PostForm.Get(`bag_id`))
+			fmt.Fprintf(w, `{"code":0,"data":{"gift_list":[{"gift_id":%s,"gift_num":1}]}}`, r.PostForm.Get(`gift_id`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+	a, closef := newTestApi(h)
+	defer closef()
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}, {Name: `SESSDATA`, Value: `s`}})
+	a.SetLocation(-12 * 3600)
+
+	if e, _ := NewMedalKeeper(MedalKeeperConf{Actions: []MedalAction{MedalByDanmu}}); !errors.Is(e, ErrMedalKeeperConf) {
+		t.Fatal(e)
+	}
+	if e, _ := NewMedalKeeper(MedalKeeperConf{Actions: []MedalAction{MedalAction(9)}}); !errors.Is(e, ErrMedalKeeperConf) {
+		t.Fatal(e)
+	}
+
+	conf := MedalKeeperConf{
+		Actions:     []MedalAction{MedalByLike, MedalByDanmu, MedalByGift},
+		MinFeed:     100,
+		LikeCount:   3,
+		Danmu:       `打卡`,
+		MaxPerMedal: 3,
+		MaxGifts:    1,
+		Store:       NewMemTaskStore(),
+	}
+	e, keeper := NewMedalKeeper(conf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var n int
+	e, res := keeper.Run(a, func(p MedalProgress) { n += 1 })
+	if e == nil || len(res) != 5 || n != 5 {
+		t.Fatal(e, res)
+	}
+	// 送礼失败，不占用额度
+	if p := res[0]; p.MedalID != 10 || p.IsLighted || len(p.Done) != 2 || p.Err == nil {
+		t.Fatal(p)
+	}
+	if p := res[1]; len(p.Done) != 3 || p.Err != nil {
+		t.Fatal(p)
+	}
+	if p := res[2]; p.Skipped != `已点亮` || len(p.Done) != 0 {
+		t.Fatal(p)
+	}
+	if p := res[3]; p.Skipped != `无直播间` || len(p.Done) != 0 {
+		t.Fatal(p)
+	}
+	if p := res[4]; p.Skipped != `达到今日亲密度上限` || len(p.Done) != 0 {
+		t.Fatal(p)
+	}
+	if len(likes) != 2 || likes[0] != `213/3` || len(danmus) != 2 || danmus[1] != `214/打卡` || len(gifts) != 1 || gifts[0] != `214/1` {
+		t.Fatal(likes, danmus, gifts)
+	}
+
+	// 同一天内仅执行剩余的额度
+	e, res = keeper.Run(a, nil)
+	if p := res[0]; p.Skipped != `达到每日上限` || len(p.Done) != 1 || p.Done[0] != MedalByLike {
+		t.Fatal(p)
+	}
+	if p := res[1]; p.Skipped != `达到每日上限` || len(p.Done) != 0 {
+		t.Fatal(p)
+	}
+	if e != nil || len(likes) != 3 || len(gifts) != 1 {
+		t.Fatal(e, likes, gifts)
+	}
+
+	// 各账号按自己的时区计日，不重置其他账号的额度
+	b, closeb := newTestApi(h)
+	defer closeb()
+	b.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `2`}, {Name: `SESSDATA`, Value: `s`}})
+	b.SetLocation(14 * 3600)
+	if e, res := keeper.Run(b, nil); len(res[0].Done) != 3 {
+		t.Fatal(e, res)
+	}
+	if _, res := keeper.Run(a, nil); res[0].Skipped != `达到每日上限` || len(res[0].Done) != 0 {
+		t.Fatal(res[0])
+	}
+
+	// 额度保存于Store，重新创建后仍有效
+	_, keeper = NewMedalKeeper(conf)
+	if _, res := keeper.Run(a, nil); res[0].Skipped != `达到每日上限` || len(res[0].Done) != 0 {
+		t.Fatal(res[0])
+	}
+}
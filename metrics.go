@@ -0,0 +1,181 @@
+package biliApi
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ApiMetric 单次方法请求(含重试)的指标
+type ApiMetric struct {
+	Api        string // 方法名
+	Endpoint   string // host+path，纯数字的路径段替换为:id
+	StatusCode int    // 最后一次请求的http状态码，未请求时为0
+	Code       *int   // 最后一次请求的业务码，非json响应时为nil
+	Retries    int
+	Duration   time.Duration
+	Err        error
+}
+
+// Metrics 指标收集，需并发安全
+type Metrics interface {
+	Observe(m ApiMetric)
+}
+
+// SetMetrics implements biliApiInter.
+func (t *biliApi) SetMetrics(m Metrics) {
+	t.setConfig(func(c *biliApiConf) {
+		c.metrics = m
+	})
+}
+
+func metricEndpoint(rawURL string) string {
+	u, e := url.Parse(rawURL)
+	if e != nil {
+		return ``
+	}
+	segs := strings.Split(u.Path, `/`)
+	for i, seg := range segs {
+		if _, e := strconv.Atoi(seg); e == nil {
+			segs[i] = `:id`
+		}
+	}
+	return u.Host + strings.Join(segs, `/`)
+}
+
+// DefaultMetricsBuckets 耗时直方图的默认分桶，单位秒
+var DefaultMetricsBuckets = []float64{.05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metricsKey struct {
+	api, endpoint string
+}
+
+type metricsRequestKey struct {
+	metricsKey
+	status, code string
+}
+
+type metricsHistogram struct {
+	counts []uint64 // 与buckets对应，非累计
+	sum    float64
+	count  uint64
+}
+
+// MetricsCollector 内存中的Metrics实现，同时是输出OpenMetrics文本的http.Handler
+type MetricsCollector struct {
+	buckets   []float64
+	requests  map[metricsRequestKey]uint64
+	retries   map[metricsKey]uint64
+	durations map[metricsKey]*metricsHistogram
+	lock      sync.Mutex
+}
+
+// NewMetricsCollector buckets为耗时直方图的分桶(秒)，为空时使用DefaultMetricsBuckets
+func NewMetricsCollector(buckets ...float64) *MetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &MetricsCollector{
+		buckets:   slices.Compact(buckets),
+		requests:  make(map[metricsRequestKey]uint64),
+		retries:   make(map[metricsKey]uint64),
+		durations: make(map[metricsKey]*metricsHistogram),
+	}
+}
+
+// Observe implements Metrics.
+func (t *MetricsCollector) Observe(m ApiMetric) {
+	key := metricsKey{m.Api, m.Endpoint}
+	rkey := metricsRequestKey{metricsKey: key, status: strconv.Itoa(m.StatusCode)}
+	if m.Code != nil {
+		rkey.code = strconv.Itoa(*m.Code)
+	}
+	sec := m.Duration.Seconds()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.requests[rkey] += 1
+	if m.Retries > 0 {
+		t.retries[key] += uint64(m.Retries)
+	}
+	h, ok := t.durations[key]
+	if !ok {
+		h = &metricsHistogram{counts: make([]uint64, len(t.buckets))}
+		t.durations[key] = h
+	}
+	if i, _ := slices.BinarySearch(t.buckets, sec); i < len(t.buckets) {
+		h.counts[i] += 1
+	}
+	h.sum += sec
+	h.count += 1
+}
+
+// ServeHTTP 输出OpenMetrics文本
+func (t *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	t.writeTo(&buf)
+	w.Header().Set(`Content-Type`, `application/openmetrics-text; version=1.0.0; charset=utf-8`)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func (t *MetricsCollector) writeTo(buf *bytes.Buffer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	buf.WriteString("# TYPE bili_api_requests counter\n# HELP bili_api_requests Requests by method, endpoint, http status and bilibili code.\n")
+	rkeys := sortedKeys(t.requests, func(a, b metricsRequestKey) int {
+		return cmp.Or(compareMetricsKey(a.metricsKey, b.metricsKey), strings.Compare(a.status, b.status), strings.Compare(a.code, b.code))
+	})
+	for _, k := range rkeys {
+		fmt.Fprintf(buf, "bili_api_requests_total{method=%s,endpoint=%s,status=%s,code=%s} %d\n",
+			metricsLabel(k.api), metricsLabel(k.endpoint), metricsLabel(k.status), metricsLabel(k.code), t.requests[k])
+	}
+
+	buf.WriteString("# TYPE bili_api_retries counter\n# HELP bili_api_retries Retries by method and endpoint.\n")
+	for _, k := range sortedKeys(t.retries, compareMetricsKey) {
+		fmt.Fprintf(buf, "bili_api_retries_total{method=%s,endpoint=%s} %d\n", metricsLabel(k.api), metricsLabel(k.endpoint), t.retries[k])
+	}
+
+	buf.WriteString("# TYPE bili_api_request_duration_seconds histogram\n# HELP bili_api_request_duration_seconds Request duration including retries.\n# UNIT bili_api_request_duration_seconds seconds\n")
+	for _, k := range sortedKeys(t.durations, compareMetricsKey) {
+		h := t.durations[k]
+		labels := `method=` + metricsLabel(k.api) + `,endpoint=` + metricsLabel(k.endpoint)
+		var acc uint64
+		for i, le := range t.buckets {
+			acc += h.counts[i]
+			fmt.Fprintf(buf, "bili_api_request_duration_seconds_bucket{%s,le=\"%s\"} %d\n", labels, strconv.FormatFloat(le, 'g', -1, 64), acc)
+		}
+		fmt.Fprintf(buf, "bili_api_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(buf, "bili_api_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(buf, "bili_api_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+	buf.WriteString("# EOF\n")
+}
+
+func compareMetricsKey(a, b metricsKey) int {
+	return cmp.Or(strings.Compare(a.api, b.api), strings.Compare(a.endpoint, b.endpoint))
+}
+
+func sortedKeys[K comparable, V any](m map[K]V, f func(a, b K) int) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, f)
+	return keys
+}
+
+var metricsLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func metricsLabel(v string) string {
+	return `"` + metricsLabelReplacer.Replace(v) + `"`
+}
@@ -0,0 +1,52 @@
+package biliApi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	var n atomic.Int32
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"code":-799,"message":"请求过于频繁"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{}}}`))
+	})
+	defer closef()
+
+	m := NewMetricsCollector(10, 0.5)
+	a.SetMetrics(m)
+	a.SetRetryPolicy(`GetRoomBaseInfo`, RetryPolicy{Max: 1, Codes: []int{-799}})
+	if e, _ := a.GetRoomBaseInfo(213); e != nil {
+		t.Fatal(e)
+	}
+	m.Observe(ApiMetric{Api: `LiveHtml`, Endpoint: metricEndpoint(`https://live.bilibili.com/213`), StatusCode: 200, Duration: time.Second})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, `/metrics`, nil))
+	if !strings.HasPrefix(w.Header().Get(`Content-Type`), `application/openmetrics-text`) {
+		t.Fatal(w.Header())
+	}
+	body := w.Body.String()
+	for _, s := range []string{
+		`bili_api_requests_total{method="GetRoomBaseInfo",endpoint="api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo",status="200",code="0"} 1`,
+		`bili_api_retries_total{method="GetRoomBaseInfo",endpoint="api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo"} 1`,
+		`bili_api_requests_total{method="LiveHtml",endpoint="live.bilibili.com/:id",status="200",code=""} 1`,
+		`bili_api_request_duration_seconds_bucket{method="LiveHtml",endpoint="live.bilibili.com/:id",le="0.5"} 0`,
+		`bili_api_request_duration_seconds_bucket{method="LiveHtml",endpoint="live.bilibili.com/:id",le="10"} 1`,
+		`bili_api_request_duration_seconds_count{method="LiveHtml",endpoint="live.bilibili.com/:id"} 1`,
+	} {
+		if !strings.Contains(body, s) {
+			t.Fatal(s, "\n", body)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Fatal(body)
+	}
+}
@@ -0,0 +1,235 @@
+package biliApi
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// ApiReq 中间件可见的请求，Before中可修改Url、Header、PostStr
+type ApiReq struct {
+	Api     string // 方法名，如GetNav
+	Method  string
+	Url     string
+	Header  map[string]string
+	PostStr string
+	Proxy   string // 可修改，用于单次请求指定代理
+	Attempt int    // 重试次数，首次请求为0
+}
+
+// ApiRes 中间件可见的响应
+type ApiRes struct {
+	StatusCode int
+	Body       []byte // 仅在After调用期间有效
+	Err        error
+	Duration   time.Duration
+}
+
+// Middleware 请求中间件，Before按添加顺序调用，After按添加逆序调用，重试时每次均会调用
+type Middleware struct {
+	Before func(req *ApiReq) error // 返回错误时不再请求，该错误作为请求结果
+	After  func(req *ApiReq, res *ApiRes)
+}
+
+// AddMiddleware implements biliApiInter.
+func (t *biliApi) AddMiddleware(m Middleware) {
+	t.setConfig(func(c *biliApiConf) {
+		c.middlewares = append(slices.Clip(c.middlewares), m)
+	})
+}
+
+// 设备指纹本身的请求，不应再触发设备指纹生成
+var fingerprintApis = []string{`getSpi`, `exClimbWuzhi`}
+
+// do 所有请求的执行入口
+func (t *biliApi) do(c *biliApiConf, req *reqf.Req, api string, rv reqf.Rval) (err error) {
+	if !c.fingerprintOff && !slices.Contains(fingerprintApis, api) {
+		t.ensureFingerprint()
+	}
+
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	rv.Proxy = c.proxy
+	rv.DisableSystemProxy = c.disableSystemProxy
+	rv.Header = t.header(c, api, rv.Header)
+	if _, ok := rv.Header[`Cookie`]; !ok {
+		if cookie := t.cookiesFor(rv.Url); cookie != `` {
+			rv.Header[`Cookie`] = cookie
+		}
+	}
+	if _, ok := rv.Header[`Content-Type`]; !ok && rv.PostStr != `` {
+		rv.Header[`Content-Type`] = `application/x-www-form-urlencoded`
+	}
+
+	// 重试由policy决定
+	rv.Retry = 0
+	policy := c.retryPolicy(api)
+	family := familyOf(rv.Url)
+	limiter := t.limiter(family)
+	breaker := t.breaker(family)
+	room := roomOf(rv.Url, rv.PostStr)
+
+	var (
+		attempt    int
+		statusCode int
+		code       *int
+	)
+	if c.metrics != nil {
+		start := time.Now()
+		defer func() {
+			c.metrics.Observe(ApiMetric{
+				Api:        api,
+				Endpoint:   metricEndpoint(rv.Url),
+				StatusCode: statusCode,
+				Code:       code,
+				Retries:    attempt,
+				Duration:   time.Since(start),
+				Err:        err,
+			})
+		}()
+	}
+
+	for ; ; attempt++ {
+		if err = limiter.wait(ctx); err != nil {
+			return
+		}
+
+		probe, e := t.allowBreaker(breaker, api)
+		if e != nil {
+			return e
+		}
+
+		if t.opt.Proxy != `` {
+			rv.Proxy = t.opt.Proxy
+		} else if c.proxyPool != nil {
+			if rv.Proxy, err = c.proxyPool.pick(room); err != nil {
+				breaker.done(probe, breakerNeutral, 0, time.Now())
+				return
+			}
+		}
+
+		var (
+			sent  bool
+			proxy string
+		)
+		attemptStart := time.Now()
+		err, statusCode, sent, proxy = t.doOnce(c, req, api, rv, attempt)
+		code = respCode(req, err)
+		if sent && c.logger != nil {
+			var body []byte
+			_ = req.Respon(func(b []byte) error {
+				body = b
+				return nil
+			})
+			c.logAttempt(ctx, api, reqMethod(rv), rv.Url, rv.PostStr, attempt, statusCode, code, body, time.Since(attemptStart), err)
+		}
+		result, riskCode := breaker.result(sent, statusCode, code)
+		breaker.done(probe, result, riskCode, time.Now())
+		if c.proxyPool != nil && sent {
+			c.proxyPool.report(proxy, statusCode, code)
+		}
+
+		if !sent || attempt >= policy.Max || !policy.shouldRetry(err, statusCode, code) {
+			return
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// doOnce 经中间件执行一次请求，sent为false时请求被中间件拦截，proxy为实际使用的代理
+func (t *biliApi) doOnce(c *biliApiConf, req *reqf.Req, api string, rv reqf.Rval, attempt int) (err error, statusCode int, sent bool, proxy string) {
+	statusf := func() {
+		_ = req.Response(func(r *http.Response) error {
+			if r != nil {
+				statusCode = r.StatusCode
+			}
+			return nil
+		})
+	}
+
+	if len(c.middlewares) == 0 {
+		err = req.Reqf(rv)
+		statusf()
+		return err, statusCode, true, rv.Proxy
+	}
+
+	ar := &ApiReq{
+		Api:     api,
+		Url:     rv.Url,
+		Header:  maps.Clone(rv.Header),
+		PostStr: rv.PostStr,
+		Proxy:   rv.Proxy,
+		Attempt: attempt,
+	}
+	ar.Method = reqMethod(rv)
+
+	res := &ApiRes{}
+	for i := 0; i < len(c.middlewares) && err == nil; i++ {
+		if f := c.middlewares[i].Before; f != nil {
+			err = f(ar)
+		}
+	}
+
+	start := time.Now()
+	if err == nil {
+		rv.Url, rv.Header, rv.PostStr, rv.Proxy = ar.Url, ar.Header, ar.PostStr, ar.Proxy
+		sent, proxy = true, ar.Proxy
+		err = req.Reqf(rv)
+		res.Duration = time.Since(start)
+		statusf()
+		res.StatusCode = statusCode
+		_ = req.Respon(func(b []byte) error {
+			res.Body = b
+			return nil
+		})
+	}
+	res.Err = err
+
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		if f := c.middlewares[i].After; f != nil {
+			f(ar, res)
+		}
+	}
+	return
+}
+
+func reqMethod(rv reqf.Rval) string {
+	if rv.Method != `` {
+		return rv.Method
+	} else if rv.PostStr == `` {
+		return http.MethodGet
+	}
+	return http.MethodPost
+}
+
+// respCode 返回json响应中的code，非json时返回nil
+func respCode(req *reqf.Req, err error) (code *int) {
+	if err != nil {
+		return
+	}
+	_ = req.Respon(func(b []byte) error {
+		var j struct {
+			Code *int `json:"code"`
+		}
+		if json.Unmarshal(b, &j) == nil {
+			code = j.Code
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,267 @@
+package biliApi
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoProxy = errors.New(`ErrNoProxy`)
+
+// ProxyStrategy 代理选择策略
+type ProxyStrategy int
+
+const (
+	ProxyRoundRobin  ProxyStrategy = iota // 轮询
+	ProxyLeastErrors                      // 错误最少的优先
+	ProxySticky                           // 同一直播间固定使用同一代理，无直播间的请求轮询
+)
+
+// ProxyState 代理状态，用于监控
+type ProxyState struct {
+	Proxy        string
+	Healthy      bool      // 最近一次健康检查是否通过
+	EvictedUntil time.Time // 因风控响应暂停使用至此时间
+	Requests     uint64
+	Errors       uint64 // 网络错误、5xx及风控响应数
+}
+
+// ProxyPool 代理池，可在多个实例间共用
+type ProxyPool struct {
+	strategy ProxyStrategy
+	evictFor time.Duration
+	codes    []int // 视为风控的业务码
+	proxies  []*ProxyState
+	next     int
+	sticky   map[string]string // 直播间->代理
+	lock     sync.Mutex
+}
+
+// NewProxyPool proxy格式同SetProxy，如http://127.0.0.1:8080、socks5://127.0.0.1:1080
+func NewProxyPool(strategy ProxyStrategy, proxies ...string) *ProxyPool {
+	t := &ProxyPool{
+		strategy: strategy,
+		evictFor: 10 * time.Minute,
+		codes:    []int{-352, -412},
+		sticky:   make(map[string]string),
+	}
+	for _, p := range proxies {
+		t.Add(p)
+	}
+	return t
+}
+
+// SetEviction 设置出现风控响应后暂停使用代理的时间，codes为视为风控的业务码，为空时不变，http 412总是视为风控
+func (t *ProxyPool) SetEviction(d time.Duration, codes ...int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.evictFor = d
+	if len(codes) > 0 {
+		t.codes = slices.Clone(codes)
+	}
+}
+
+// Add 添加代理，已存在时忽略
+func (t *ProxyPool) Add(proxy string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.find(proxy) == nil {
+		t.proxies = append(t.proxies, &ProxyState{Proxy: proxy, Healthy: true})
+	}
+}
+
+// Remove 移除代理
+func (t *ProxyPool) Remove(proxy string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.proxies = slices.DeleteFunc(t.proxies, func(p *ProxyState) bool {
+		return p.Proxy == proxy
+	})
+	t.unstick(proxy)
+}
+
+// Evict 暂停使用代理d时间
+func (t *ProxyPool) Evict(proxy string, d time.Duration) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if p := t.find(proxy); p != nil {
+		p.EvictedUntil = time.Now().Add(d)
+		t.unstick(proxy)
+	}
+}
+
+// unstick 移除固定至proxy的直播间，持有lock时调用
+func (t *ProxyPool) unstick(proxy string) {
+	maps.DeleteFunc(t.sticky, func(_ string, v string) bool {
+		return v == proxy
+	})
+}
+
+// States 获取各代理的状态
+func (t *ProxyPool) States() (states []ProxyState) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, p := range t.proxies {
+		states = append(states, *p)
+	}
+	return
+}
+
+func (t *ProxyPool) find(proxy string) *ProxyState {
+	for _, p := range t.proxies {
+		if p.Proxy == proxy {
+			return p
+		}
+	}
+	return nil
+}
+
+func (t *ProxyState) available(now time.Time) bool {
+	return t.Healthy && !now.Before(t.EvictedUntil)
+}
+
+// pick 按策略选择代理，room为空时不使用固定代理
+func (t *ProxyPool) pick(room string) (proxy string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	if t.strategy == ProxySticky && room != `` {
+		if p := t.find(t.sticky[room]); p != nil && p.available(now) {
+			p.Requests += 1
+			return p.Proxy, nil
+		}
+	}
+
+	var picked *ProxyState
+	for i := 0; i < len(t.proxies); i++ {
+		p := t.proxies[(t.next+i)%len(t.proxies)]
+		if !p.available(now) {
+			continue
+		}
+		if picked == nil {
+			picked = p
+			if t.strategy != ProxyLeastErrors {
+				break
+			}
+		} else if p.Errors < picked.Errors {
+			picked = p
+		}
+	}
+	if picked == nil {
+		return ``, ErrNoProxy
+	}
+	t.next = (slices.Index(t.proxies, picked) + 1) % len(t.proxies)
+	if t.strategy == ProxySticky && room != `` {
+		t.sticky[room] = picked.Proxy
+	}
+	picked.Requests += 1
+	return picked.Proxy, nil
+}
+
+// report 记录请求结果，风控响应时暂停使用该代理
+func (t *ProxyPool) report(proxy string, statusCode int, code *int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	p := t.find(proxy)
+	if p == nil {
+		return
+	}
+	switch {
+	case statusCode == 412 || (code != nil && slices.Contains(t.codes, *code)):
+		p.Errors += 1
+		p.EvictedUntil = time.Now().Add(t.evictFor)
+		t.unstick(proxy)
+	case statusCode == 0 || statusCode >= 500:
+		p.Errors += 1
+	}
+}
+
+func (t *ProxyPool) setHealthy(proxy string, healthy bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if p := t.find(proxy); p != nil {
+		p.Healthy = healthy
+	}
+}
+
+// HealthCheck 检查所有代理，通过IsConnected判断可用性
+func (t *ProxyPool) HealthCheck() {
+	t.lock.Lock()
+	proxies := make([]string, 0, len(t.proxies))
+	for _, p := range t.proxies {
+		proxies = append(proxies, p.Proxy)
+	}
+	t.lock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, proxy := range proxies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := newBiliApi(newReqPool())
+			a.setConfig(func(c *biliApiConf) {
+				c.fingerprintOff = true
+			})
+			a.SetProxy(proxy)
+			a.SetDisableSystemProxy(true)
+			t.setHealthy(proxy, a.IsConnected() == nil)
+		}()
+	}
+	wg.Wait()
+}
+
+// StartHealthCheck 每interval检查一次，直至ctx结束
+func (t *ProxyPool) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			t.HealthCheck()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SetProxyPool implements biliApiInter.
+func (t *biliApi) SetProxyPool(p *ProxyPool) {
+	t.setConfig(func(c *biliApiConf) {
+		c.proxyPool = p
+	})
+}
+
+// roomOf 从请求中取直播间号，用于固定代理
+func roomOf(rawURL, postStr string) string {
+	u, e := url.Parse(rawURL)
+	if e != nil {
+		return ``
+	}
+	vals := u.Query()
+	if post, e := url.ParseQuery(postStr); e == nil {
+		for k, v := range post {
+			vals[k] = append(vals[k], v...)
+		}
+	}
+	for _, k := range []string{`room_id`, `roomid`, `roomId`, `room_ids`} {
+		if v := vals.Get(k); v != `` {
+			return v
+		}
+	}
+	// 直播间页面
+	if path := strings.Trim(u.Path, `/`); u.Hostname() == `live.bilibili.com` {
+		if _, e := strconv.Atoi(path); e == nil {
+			return path
+		}
+	}
+	return ``
+}
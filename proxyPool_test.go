@@ -0,0 +1,122 @@
+package biliApi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRoomOf(t *testing.T) {
+	for u, r := range map[string]string{
+		`https://api.live.bilibili.com/room/v1/Room/get_info?room_id=213`:                        `213`,
+		`https://api.live.bilibili.com/xlive/web-room/v1/index/getRoomBaseInfo?room_ids=213&a=b`: `213`,
+		`https://live.bilibili.com/213`:                                                          `213`,
+		`https://api.bilibili.com/x/web-interface/nav`:                                           ``,
+	} {
+		if roomOf(u, ``) != r {
+			t.Fatal(u)
+		}
+	}
+	if roomOf(`https://api.live.bilibili.com/xlive/app-ucenter/v1/like_info_v3/like/likeReportV3`, `roomid=213&uid=1`) != `213` {
+		t.Fatal()
+	}
+}
+
+func TestProxyPool(t *testing.T) {
+	var (
+		hits [2]atomic.Int32
+		risk atomic.Bool
+	)
+	var proxies [2]*httptest.Server
+	for i := range proxies {
+		proxies[i] = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i].Add(1)
+			if i == 1 && risk.Load() {
+				_, _ = w.Write([]byte(`{"code":-352,"message":"-352"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{}}}`))
+		}))
+		defer proxies[i].Close()
+	}
+
+	a := newBiliApi(newReqPool())
+	a.setConfig(func(c *biliApiConf) {
+		c.fingerprintOff = true
+	})
+	a.SetCacheTTL(`GetRoomBaseInfo`, 0)
+	a.SetBreaker(FamilyLive, BreakerConf{})
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			// 以http请求，使代理可直接响应
+			req.Url = `http` + req.Url[len(`https`):]
+			return nil
+		},
+	})
+
+	// 轮询
+	p := NewProxyPool(ProxyRoundRobin, proxies[0].URL, proxies[1].URL)
+	a.SetProxyPool(p)
+	for i := 0; i < 4; i++ {
+		if e, _ := a.GetRoomBaseInfo(213); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if hits[0].Load() != 2 || hits[1].Load() != 2 {
+		t.Fatal(hits[0].Load(), hits[1].Load())
+	}
+
+	// 风控响应时暂停使用
+	risk.Store(true)
+	for i := 0; i < 4; i++ {
+		_, _ = a.GetRoomBaseInfo(213)
+	}
+	if hits[0].Load() != 5 || hits[1].Load() != 3 {
+		t.Fatal(hits[0].Load(), hits[1].Load())
+	}
+	if s := p.States(); s[1].EvictedUntil.IsZero() || s[1].Errors != 1 || s[0].Requests != 5 {
+		t.Fatal(s)
+	}
+
+	// 固定代理
+	risk.Store(false)
+	hits[0].Store(0)
+	hits[1].Store(0)
+	p = NewProxyPool(ProxySticky, proxies[0].URL, proxies[1].URL)
+	a.SetProxyPool(p)
+	for i := 0; i < 3; i++ {
+		for _, room := range []int{1, 2} {
+			if e, _ := a.GetRoomBaseInfo(room); e != nil {
+				t.Fatal(e)
+			}
+		}
+	}
+	if hits[0].Load() != 3 || hits[1].Load() != 3 {
+		t.Fatal(hits[0].Load(), hits[1].Load())
+	}
+
+	// 单次请求指定代理
+	for i := 0; i < 2; i++ {
+		_, _ = a.With(CallOpt{Proxy: proxies[1].URL}).GetRoomBaseInfo(1)
+	}
+	if hits[1].Load() != 5 {
+		t.Fatal(hits[1].Load())
+	}
+
+	// 移除、暂停使用的代理不再被固定
+	p.Evict(proxies[0].URL, time.Minute)
+	p.Remove(proxies[1].URL)
+	if len(p.sticky) != 0 {
+		t.Fatal(p.sticky)
+	}
+
+	// 健康检查不通过
+	p = NewProxyPool(ProxyLeastErrors, `http://127.0.0.1:1`)
+	p.HealthCheck()
+	a.SetProxyPool(p)
+	if e, _ := a.GetRoomBaseInfo(213); e != ErrNoProxy {
+		t.Fatal(e)
+	}
+}
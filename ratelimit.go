@@ -0,0 +1,182 @@
+package biliApi
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrRateLimitQueueFull = errors.New(`ErrRateLimitQueueFull`)
+
+// ApiFamily 接口族，按host区分，各自限速
+type ApiFamily string
+
+const (
+	FamilyLive     ApiFamily = `live`     // *.live.bilibili.com
+	FamilyMain     ApiFamily = `main`     // 其他*.bilibili.com
+	FamilyPassport ApiFamily = `passport` // passport.bilibili.com
+)
+
+func familyOf(rawURL string) ApiFamily {
+	u, e := url.Parse(rawURL)
+	if e != nil {
+		return FamilyMain
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case domainMatch(host, `live.bilibili.com`):
+		return FamilyLive
+	case domainMatch(host, `passport.bilibili.com`):
+		return FamilyPassport
+	default:
+		return FamilyMain
+	}
+}
+
+// RateLimit 令牌桶限速
+type RateLimit struct {
+	Rate     float64 // 每秒令牌数，<=0时不限速
+	Burst    int     // 桶容量
+	MaxQueue int     // 最多排队数，超过时返回ErrRateLimitQueueFull，<=0时不限
+}
+
+var defaultRateLimit = map[ApiFamily]RateLimit{
+	FamilyLive:     {Rate: 5, Burst: 10},
+	FamilyMain:     {Rate: 5, Burst: 10},
+	FamilyPassport: {Rate: 1, Burst: 3},
+}
+
+// RateLimitStats 限速统计
+type RateLimitStats struct {
+	Allowed  uint64        // 通过数
+	Waited   uint64        // 其中需排队的数
+	Rejected uint64        // 因队列满或取消而拒绝的数
+	WaitTime time.Duration // 累计排队时间
+	Queue    int           // 当前排队数
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+	stats  RateLimitStats
+	lock   sync.Mutex
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+func (t *tokenBucket) refill(now time.Time) {
+	if t.limit.Rate <= 0 {
+		return
+	}
+	t.tokens += now.Sub(t.last).Seconds() * t.limit.Rate
+	if burst := float64(max(t.limit.Burst, 1)); t.tokens > burst {
+		t.tokens = burst
+	}
+	t.last = now
+}
+
+func (t *tokenBucket) setLimit(limit RateLimit) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.refill(time.Now())
+	t.limit = limit
+	if burst := float64(max(limit.Burst, 1)); t.tokens > burst {
+		t.tokens = burst
+	}
+}
+
+// wait 取得一个令牌，不足时排队至令牌可用或ctx结束
+func (t *tokenBucket) wait(ctx context.Context) error {
+	t.lock.Lock()
+	now := time.Now()
+	if t.limit.Rate <= 0 {
+		t.stats.Allowed += 1
+		t.lock.Unlock()
+		return nil
+	}
+	t.refill(now)
+	if t.tokens >= 1 {
+		t.tokens -= 1
+		t.stats.Allowed += 1
+		t.lock.Unlock()
+		return nil
+	}
+	if t.limit.MaxQueue > 0 && t.stats.Queue >= t.limit.MaxQueue {
+		t.stats.Rejected += 1
+		t.lock.Unlock()
+		return ErrRateLimitQueueFull
+	}
+	// 预占令牌，按欠额计算等待时间
+	t.tokens -= 1
+	delay := time.Duration(-t.tokens / t.limit.Rate * float64(time.Second))
+	t.stats.Queue += 1
+	t.lock.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		t.lock.Lock()
+		t.stats.Queue -= 1
+		t.stats.Allowed += 1
+		t.stats.Waited += 1
+		t.stats.WaitTime += time.Since(now)
+		t.lock.Unlock()
+		return nil
+	case <-ctx.Done():
+		t.lock.Lock()
+		t.stats.Queue -= 1
+		t.stats.Rejected += 1
+		t.tokens += 1
+		t.lock.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (t *tokenBucket) getStats() RateLimitStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.stats
+}
+
+// limiter 返回接口族对应的令牌桶
+func (t *biliApi) limiter(family ApiFamily) *tokenBucket {
+	t.limitersLock.Lock()
+	defer t.limitersLock.Unlock()
+	if t.limiters == nil {
+		t.limiters = make(map[ApiFamily]*tokenBucket)
+	}
+	b, ok := t.limiters[family]
+	if !ok {
+		b = newTokenBucket(defaultRateLimit[family])
+		t.limiters[family] = b
+	}
+	return b
+}
+
+// SetRateLimit implements biliApiInter.
+func (t *biliApi) SetRateLimit(family ApiFamily, limit RateLimit) {
+	t.limiter(family).setLimit(limit)
+}
+
+// GetRateLimitStats implements biliApiInter.
+func (t *biliApi) GetRateLimitStats() (stats map[ApiFamily]RateLimitStats) {
+	stats = make(map[ApiFamily]RateLimitStats)
+	for _, family := range []ApiFamily{FamilyLive, FamilyMain, FamilyPassport} {
+		stats[family] = t.limiter(family).getStats()
+	}
+	return
+}
+
+// SetContext implements biliApiInter.
+func (t *biliApi) SetContext(ctx context.Context) {
+	t.setConfig(func(c *biliApiConf) {
+		c.ctx = ctx
+	})
+}
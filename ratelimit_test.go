@@ -0,0 +1,89 @@
+package biliApi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFamilyOf(t *testing.T) {
+	for u, f := range map[string]ApiFamily{
+		`https://api.live.bilibili.com/room/v1/Room/get_info`: FamilyLive,
+		`https://live.bilibili.com/213`:                       FamilyLive,
+		`https://passport.bilibili.com/x/passport-login/web`:  FamilyPassport,
+		`https://api.bilibili.com/x/web-interface/nav`:        FamilyMain,
+		`https://www.bilibili.com/`:                           FamilyMain,
+	} {
+		if familyOf(u) != f {
+			t.Fatal(u, familyOf(u))
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 20, Burst: 2, MaxQueue: 1})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if e := b.wait(context.Background()); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if d := time.Since(start); d < 40*time.Millisecond {
+		t.Fatal(`no wait`, d)
+	}
+	if s := b.getStats(); s.Allowed != 3 || s.Waited != 1 || s.Queue != 0 {
+		t.Fatal(s)
+	}
+
+	// 排队中取消，预占的令牌归还
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- b.wait(ctx)
+	}()
+	for b.getStats().Queue == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if e := b.wait(context.Background()); e != ErrRateLimitQueueFull {
+		t.Fatal(e)
+	}
+	cancel()
+	if e := <-done; e != context.Canceled {
+		t.Fatal(e)
+	}
+	if s := b.getStats(); s.Rejected != 2 || s.Queue != 0 {
+		t.Fatal(s)
+	}
+
+	b.setLimit(RateLimit{})
+	for i := 0; i < 100; i++ {
+		if e := b.wait(context.Background()); e != nil {
+			t.Fatal(e)
+		}
+	}
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{}}}`))
+	})
+	defer closef()
+
+	a.SetCacheTTL(`GetRoomBaseInfo`, 0)
+	a.SetRateLimit(FamilyLive, RateLimit{Rate: 0.001, Burst: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	a.SetContext(ctx)
+
+	if e, _ := a.GetRoomBaseInfo(213); e != nil {
+		t.Fatal(e)
+	}
+	if e, _ := a.GetRoomBaseInfo(213); e != context.DeadlineExceeded {
+		t.Fatal(e)
+	}
+	if s := a.GetRateLimitStats()[FamilyLive]; s.Allowed != 1 || s.Rejected != 1 {
+		t.Fatal(s)
+	}
+}
@@ -0,0 +1,227 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// 关系操作，x/relation/modify的act
+const (
+	relationFollow   = 1
+	relationUnfollow = 2
+)
+
+// 已关注时再次关注的业务码
+const relationCodeFollowed = 22014
+
+// Relation 与某用户的关系
+type Relation struct {
+	Mid        int
+	Following  bool // 已关注对方，包括悄悄关注
+	Whisper    bool // 悄悄关注
+	FollowedBy bool // 对方已关注自己
+	Blocked    bool // 已拉黑对方
+	Special    bool // 特别关注
+	Tags       []int
+	Mtime      time.Time // 关注时间
+}
+
+type relationAttr struct {
+	Mid       int   `json:"mid"`
+	Attribute int   `json:"attribute"` // 0未关注 1悄悄关注 2已关注 6互相关注 128拉黑
+	Mtime     int64 `json:"mtime"`
+	Tag       []int `json:"tag"`
+	Special   int   `json:"special"`
+}
+
+func (t relationAttr) toRelation() Relation {
+	r := Relation{
+		Mid:        t.Mid,
+		Following:  t.Attribute == 1 || t.Attribute == 2 || t.Attribute == 6,
+		Whisper:    t.Attribute == 1,
+		FollowedBy: t.Attribute == 6,
+		Blocked:    t.Attribute == 128,
+		Special:    t.Special == 1,
+		Tags:       t.Tag,
+	}
+	if t.Mtime != 0 {
+		r.Mtime = time.Unix(t.Mtime, 0)
+	}
+	return r
+}
+
+// Follow implements biliApiInter.
+func (t *biliApi) Follow(uid int) (err error) {
+	return t.modifyRelation(`Follow`, uid, relationFollow)
+}
+
+// Unfollow implements biliApiInter.
+func (t *biliApi) Unfollow(uid int) (err error) {
+	return t.modifyRelation(`Unfollow`, uid, relationUnfollow)
+}
+
+func (t *biliApi) modifyRelation(api string, uid, act int) (err error) {
+	c := t.config()
+	if !t.IsLogin() {
+		return ErrNeedLogin
+	}
+	e, csrf := t.GetCookie(`bili_jct`)
+	if e != nil {
+		return ErrNeedLogin
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, api, reqf.Rval{
+		Url:     `https://api.bilibili.com/x/relation/modify`,
+		PostStr: fmt.Sprintf("fid=%d&act=%d&re_src=11&csrf=%s", uid, act, csrf),
+		Header: map[string]string{
+			`Content-Type`: `application/x-www-form-urlencoded`,
+			`Referer`:      fmt.Sprintf("https://space.bilibili.com/%d", uid),
+		},
+		Timeout: 10 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 && !(act == relationFollow && j.Code == relationCodeFollowed) {
+		return errors.New(j.Message)
+	}
+	// 名片中含是否已关注
+	t.respCache.invalidate(`GetUserCard`)
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
+
+// GetRelation implements biliApiInter.
+func (t *biliApi) GetRelation(uid int) (err error, res Relation) {
+	c := t.config()
+	if !t.IsLogin() {
+		err = ErrNeedLogin
+		return
+	}
+
+	query := "mid=" + strconv.Itoa(uid)
+	if e, queryE := t.wbiSign(query); e != nil {
+		err = e
+		return
+	} else {
+		query = queryE
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetRelation", reqf.Rval{
+		Url: "https://api.bilibili.com/x/space/wbi/acc/relation?" + query,
+		Header: map[string]string{
+			`Referer`: fmt.Sprintf("https://space.bilibili.com/%d", uid),
+		},
+		Timeout: 10 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Relation   relationAttr `json:"relation"`
+			BeRelation relationAttr `json:"be_relation"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		if j.Code == -352 {
+			t.wbi.invalidate()
+		}
+		err = errors.New(j.Message)
+		return
+	}
+
+	res = j.Data.Relation.toRelation()
+	res.Mid = uid
+	res.FollowedBy = j.Data.BeRelation.Attribute == 2 || j.Data.BeRelation.Attribute == 6
+	return
+}
+
+// GetRelations implements biliApiInter.
+func (t *biliApi) GetRelations(uids ...int) (err error, res map[int]Relation) {
+	c := t.config()
+	if !t.IsLogin() {
+		err = ErrNeedLogin
+		return
+	}
+
+	res = make(map[int]Relation, len(uids))
+	if len(uids) == 0 {
+		return
+	}
+	fids := make([]string, len(uids))
+	for i, uid := range uids {
+		fids[i] = strconv.Itoa(uid)
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetRelations", reqf.Rval{
+		Url: "https://api.bilibili.com/x/relation/relations?fids=" + strings.Join(fids, `,`),
+		Header: map[string]string{
+			`Referer`: `https://www.bilibili.com/`,
+		},
+		Timeout: 10 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int                     `json:"code"`
+		Message string                  `json:"message"`
+		Data    map[string]relationAttr `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	}
+
+	// 未关注的用户不在返回中
+	for _, uid := range uids {
+		res[uid] = Relation{Mid: uid}
+	}
+	for k, v := range j.Data {
+		if uid, e := strconv.Atoi(k); e == nil {
+			r := v.toRelation()
+			r.Mid = uid
+			res[uid] = r
+		}
+	}
+	return
+}
@@ -0,0 +1,94 @@
+package biliApi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRelation(t *testing.T) {
+	var modify []string
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		switch r.URL.Path {
+		case `/x/relation/modify`:
+			if r.PostForm.Get(`csrf`) != `jct` {
+				t.Error(r.PostForm)
+			}
+			modify = append(modify, r.PostForm.Get(`fid`)+`/`+r.PostForm.Get(`act`))
+			if r.PostForm.Get(`fid`) == `3` {
+				_, _ = w.Write([]byte(`{"code":22014,"message":"已经关注用户，无法重复关注"}`))
+			} else if r.PostForm.Get(`fid`) == `4` {
+				_, _ = w.Write([]byte(`{"code":22002,"message":"由于该用户隐私设置，关注失败"}`))
+			} else {
+				_, _ = w.Write([]byte(`{"code":0}`))
+			}
+		case `/x/space/wbi/acc/relation`:
+			if q := r.URL.Query(); q.Get(`w_rid`) == `` {
+				t.Error(q)
+			} else if q.Get(`mid`) != `2` {
+				_, _ = w.Write([]byte(`{"code":-352,"message":"风控校验失败"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"relation":{"mid":2,"attribute":2,"mtime":1700000000,"tag":[-10],"special":1},"be_relation":{"mid":1,"attribute":2}}}`))
+		case `/x/relation/relations`:
+			if fids := r.URL.Query().Get(`fids`); fids != `2,3,5` {
+				t.Error(fids)
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"2":{"mid":2,"attribute":6,"mtime":1700000000,"tag":null,"special":0},"3":{"mid":3,"attribute":128}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closef()
+
+	if e := a.Follow(2); !errors.Is(e, ErrNeedLogin) {
+		t.Fatal(e)
+	}
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `jct`}, {Name: `DedeUserID`, Value: `1`}, {Name: `SESSDATA`, Value: `s`}})
+	a.wbi.set(`https://i0.hdslb.com/bfs/wbi/a.png`, `https://i0.hdslb.com/bfs/wbi/b.png`, time.Now())
+
+	if e := a.Follow(2); e != nil {
+		t.Fatal(e)
+	}
+	// 已关注
+	if e := a.Follow(3); e != nil {
+		t.Fatal(e)
+	}
+	if e := a.Follow(4); e == nil {
+		t.Fatal()
+	}
+	if e := a.Unfollow(2); e != nil {
+		t.Fatal(e)
+	}
+	if len(modify) != 4 || modify[0] != `2/1` || modify[3] != `2/2` {
+		t.Fatal(modify)
+	}
+
+	e, r := a.GetRelation(2)
+	if e != nil || r.Mid != 2 || !r.Following || r.Whisper || !r.FollowedBy || r.Blocked || !r.Special || len(r.Tags) != 1 || r.Mtime.Unix() != 1700000000 {
+		t.Fatal(e, r)
+	}
+	// -352时重新获取wbi key
+	if e, _ := a.GetRelation(3); e == nil {
+		t.Fatal()
+	}
+	if _, _, ok := a.wbi.get(time.Now()); ok {
+		t.Fatal()
+	}
+
+	e, rs := a.GetRelations(2, 3, 5)
+	if e != nil || len(rs) != 3 {
+		t.Fatal(e, rs)
+	}
+	if r := rs[2]; !r.Following || !r.FollowedBy {
+		t.Fatal(r)
+	}
+	if r := rs[3]; r.Following || !r.Blocked {
+		t.Fatal(r)
+	}
+	if r := rs[5]; r.Mid != 5 || r.Following || !r.Mtime.IsZero() {
+		t.Fatal(r)
+	}
+}
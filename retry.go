@@ -0,0 +1,90 @@
+package biliApi
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	Max      int           // 最多重试次数，<=0时不重试
+	Base     time.Duration // 首次重试前的等待，之后每次翻倍
+	MaxDelay time.Duration // 单次等待的上限
+	NetErr   bool          // 网络错误、5xx时是否重试，请求可能已被执行，非幂等操作不应开启
+	Codes    []int         // 需重试的业务码，如-509繁忙、-799请求过于频繁，此时请求未被执行
+}
+
+// DefaultRetryPolicy 未单独设置的方法使用的策略
+var DefaultRetryPolicy = RetryPolicy{
+	Max:      2,
+	Base:     500 * time.Millisecond,
+	MaxDelay: 5 * time.Second,
+	NetErr:   true,
+	Codes:    []int{-509, -799},
+}
+
+// 非幂等的操作，仅在业务码表明未执行时重试
+var apiRetryPolicy = map[string]RetryPolicy{
+	`DoSign`:      nonIdempotentRetryPolicy,
+	`Silver2coin`: nonIdempotentRetryPolicy,
+	`LikeReport`:  nonIdempotentRetryPolicy,
+	`SendDanmu`:   nonIdempotentRetryPolicy,
+	`SendGift`:    nonIdempotentRetryPolicy,
+	`SendBagGift`: nonIdempotentRetryPolicy,
+}
+
+var nonIdempotentRetryPolicy = RetryPolicy{
+	Max:      2,
+	Base:     500 * time.Millisecond,
+	MaxDelay: 5 * time.Second,
+	Codes:    []int{-509, -799},
+}
+
+// SetRetryPolicy implements biliApiInter.
+func (t *biliApi) SetRetryPolicy(api string, p RetryPolicy) {
+	t.setConfig(func(c *biliApiConf) {
+		c.retryPolicies = maps.Clone(c.retryPolicies)
+		if c.retryPolicies == nil {
+			c.retryPolicies = make(map[string]RetryPolicy)
+		}
+		c.retryPolicies[api] = p
+	})
+}
+
+func (c *biliApiConf) retryPolicy(api string) RetryPolicy {
+	if p, ok := c.retryPolicies[api]; ok {
+		return p
+	} else if p, ok := apiRetryPolicy[api]; ok {
+		return p
+	}
+	return DefaultRetryPolicy
+}
+
+// shouldRetry statusCode为0时视为网络错误，code为nil时视为非json响应
+func (t RetryPolicy) shouldRetry(err error, statusCode int, code *int) bool {
+	switch {
+	case statusCode >= 500 || (err != nil && statusCode == 0):
+		return t.NetErr
+	case err == nil && code != nil:
+		return slices.Contains(t.Codes, *code)
+	default:
+		return false
+	}
+}
+
+// backoff 第attempt次重试前的等待，指数增长，随机取[d/2,d]
+func (t RetryPolicy) backoff(attempt int) time.Duration {
+	d := t.Base
+	for i := 0; i < attempt && (t.MaxDelay <= 0 || d < t.MaxDelay); i++ {
+		d *= 2
+	}
+	if t.MaxDelay > 0 && d > t.MaxDelay {
+		d = t.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
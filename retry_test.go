@@ -0,0 +1,67 @@
+package biliApi
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{Base: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond, Codes: []int{-799}}
+	for attempt, max := range []time.Duration{100, 200, 300, 300} {
+		if d := p.backoff(attempt); d < max*time.Millisecond/2 || d > max*time.Millisecond {
+			t.Fatal(attempt, d)
+		}
+	}
+
+	code := -799
+	if !p.shouldRetry(nil, 200, &code) || p.shouldRetry(http.ErrHandlerTimeout, 0, nil) || p.shouldRetry(nil, 502, nil) {
+		t.Fatal()
+	}
+	p.NetErr = true
+	if !p.shouldRetry(http.ErrHandlerTimeout, 0, nil) || !p.shouldRetry(nil, 502, nil) {
+		t.Fatal()
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var n atomic.Int32
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		switch n.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			_, _ = w.Write([]byte(`{"code":-799,"message":"请求过于频繁"}`))
+		default:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"by_room_ids":{}}}`))
+		}
+	})
+	defer closef()
+	a.SetRetryPolicy(`GetRoomBaseInfo`, RetryPolicy{Max: 2, NetErr: true, Codes: []int{-799}})
+
+	if e, _ := a.GetRoomBaseInfo(213); e != nil || n.Load() != 3 {
+		t.Fatal(e, n.Load())
+	}
+
+	// 非幂等操作，5xx不重试
+	n.Store(0)
+	a.SetCookies([]*http.Cookie{{Name: `bili_jct`, Value: `1`}, {Name: `DedeUserID`, Value: `1`}})
+	a.SetRetryPolicy(`DoSign`, RetryPolicy{Max: 2, Codes: []int{-799}})
+	if e, _ := a.DoSign(); e == nil || n.Load() != 1 {
+		t.Fatal(e, n.Load())
+	}
+
+	// 中间件拦截时不重试
+	n.Store(0)
+	var attempts int
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			attempts += 1
+			return http.ErrAbortHandler
+		},
+	})
+	if e, _ := a.GetRoomBaseInfo(213); e != http.ErrAbortHandler || attempts != 1 || n.Load() != 0 {
+		t.Fatal(e, attempts, n.Load())
+	}
+}
@@ -0,0 +1,348 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// SearchType 搜索类型
+type SearchType string
+
+const (
+	SearchLiveRoom SearchType = `live_room` // 直播间
+	SearchLiveUser SearchType = `live_user` // 主播
+	SearchBiliUser SearchType = `bili_user` // 用户
+	SearchVideo    SearchType = `video`     // 视频
+)
+
+// SearchOpt 搜索选项，零值为默认
+type SearchOpt struct {
+	Type     SearchType // 为空时为SearchVideo
+	Page     int        // 从1开始
+	PageSize int        // 仅直播间、主播
+	Order    string     // 视频:totalrank click pubdate dm stow；用户:0 fans level；直播间、主播:online live_time
+	OrderAsc bool       // 升序，仅用户
+	Tids     int        // 视频分区
+	AreaName string     // 直播分区名，仅直播间，接口不支持，于每页结果中过滤
+}
+
+// SearchResult 搜索结果，仅opt.Type对应的项有值
+type SearchResult struct {
+	NumResults int // 未经AreaName过滤的结果数
+	NumPages   int // 未经AreaName过滤的页数，过滤后某页可能为空而之后的页仍有结果，应翻页至此
+	LiveRooms  []SearchLiveRoomItem
+	LiveUsers  []SearchLiveUserItem
+	BiliUsers  []SearchBiliUserItem
+	Videos     []SearchVideoItem
+}
+
+type SearchLiveRoomItem struct {
+	Roomid     int
+	Uid        int
+	Uname      string
+	Face       string
+	Title      string
+	Cover      string
+	Online     int
+	LiveStatus int
+	LiveTime   string // 2006-01-02 15:04:05
+	AreaName   string
+	Tags       string
+}
+
+type SearchLiveUserItem struct {
+	Uid        int
+	Uname      string
+	Face       string
+	Roomid     int
+	LiveStatus int
+	Attentions int // 粉丝数
+	Tags       string
+}
+
+type SearchBiliUserItem struct {
+	Mid      int
+	Uname    string
+	Face     string
+	Sign     string
+	Level    int
+	Fans     int
+	Videos   int
+	Roomid   int
+	IsLive   bool
+	Official UserOfficial
+}
+
+type SearchVideoItem struct {
+	Aid         int
+	Bvid        string
+	Title       string
+	Description string
+	Author      string
+	Mid         int
+	Pic         string
+	Play        int
+	Danmaku     int
+	Duration    string // 如 12:34
+	Pubdate     int64
+	TypeName    string
+	Tag         string
+}
+
+var searchHighlight = regexp.MustCompile(`</?em[^>]*>`)
+
+// stripHighlight 去除关键词高亮标签并反转义
+func stripHighlight(s string) string {
+	return html.UnescapeString(searchHighlight.ReplaceAllString(s, ``))
+}
+
+// Search implements biliApiInter.
+func (t *biliApi) Search(keyword string, opt SearchOpt) (err error, res SearchResult) {
+	return t.search(`Search`, keyword, opt)
+}
+
+// search api用于中间件、指标、请求头模板等，SearchUP沿用原名
+func (t *biliApi) search(api string, keyword string, opt SearchOpt) (err error, res SearchResult) {
+	c := t.config()
+
+	if opt.Type == `` {
+		opt.Type = SearchVideo
+	}
+	if opt.Page <= 0 {
+		opt.Page = 1
+	}
+	q := url.Values{}
+	q.Set(`search_type`, string(opt.Type))
+	q.Set(`keyword`, keyword)
+	q.Set(`page`, strconv.Itoa(opt.Page))
+	q.Set(`from_source`, `web_search`)
+	q.Set(`platform`, `pc`)
+	if opt.PageSize > 0 {
+		q.Set(`page_size`, strconv.Itoa(opt.PageSize))
+	}
+	if opt.Order != `` {
+		q.Set(`order`, opt.Order)
+	}
+	if opt.OrderAsc {
+		q.Set(`order_sort`, `1`)
+	}
+	if opt.Tids != 0 {
+		q.Set(`tids`, strconv.Itoa(opt.Tids))
+	}
+
+	query := q.Encode()
+	if e, queryE := t.wbiSign(query); e != nil {
+		err = e
+		return
+	} else {
+		query = queryE
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, api, reqf.Rval{
+		Url: "https://api.bilibili.com/x/web-interface/wbi/search/type?" + query,
+		Header: map[string]string{
+			`Origin`:  `https://search.bilibili.com`,
+			`Referer`: `https://search.bilibili.com/all?keyword=` + url.QueryEscape(keyword),
+		},
+		Timeout: 10 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			NumResults int             `json:"numResults"`
+			NumPages   int             `json:"numPages"`
+			Result     json.RawMessage `json:"result"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		if j.Code == -352 {
+			t.wbi.invalidate()
+		}
+		err = errors.New(j.Message)
+		return
+	}
+	res.NumResults, res.NumPages = j.Data.NumResults, j.Data.NumPages
+
+	// 无结果时result可能不是数组
+	if len(j.Data.Result) == 0 || j.Data.Result[0] != '[' {
+		return
+	}
+	switch opt.Type {
+	case SearchLiveRoom:
+		var list []struct {
+			Roomid     int    `json:"roomid"`
+			Uid        int    `json:"uid"`
+			Uname      string `json:"uname"`
+			Uface      string `json:"uface"`
+			Title      string `json:"title"`
+			UserCover  string `json:"user_cover"`
+			Online     int    `json:"online"`
+			LiveStatus int    `json:"live_status"`
+			LiveTime   string `json:"live_time"`
+			CateName   string `json:"cate_name"`
+			Tags       string `json:"tags"`
+		}
+		if err = json.Unmarshal(j.Data.Result, &list); err != nil {
+			return
+		}
+		for _, v := range list {
+			if opt.AreaName != `` && stripHighlight(v.CateName) != opt.AreaName {
+				continue
+			}
+			res.LiveRooms = append(res.LiveRooms, SearchLiveRoomItem{
+				Roomid:     v.Roomid,
+				Uid:        v.Uid,
+				Uname:      stripHighlight(v.Uname),
+				Face:       v.Uface,
+				Title:      stripHighlight(v.Title),
+				Cover:      v.UserCover,
+				Online:     v.Online,
+				LiveStatus: v.LiveStatus,
+				LiveTime:   v.LiveTime,
+				AreaName:   stripHighlight(v.CateName),
+				Tags:       stripHighlight(v.Tags),
+			})
+		}
+	case SearchLiveUser:
+		var list []struct {
+			Uid        int    `json:"uid"`
+			Uname      string `json:"uname"`
+			Uface      string `json:"uface"`
+			Roomid     int    `json:"roomid"`
+			LiveStatus int    `json:"live_status"`
+			Attentions int    `json:"attentions"`
+			Tags       string `json:"tags"`
+		}
+		if err = json.Unmarshal(j.Data.Result, &list); err != nil {
+			return
+		}
+		for _, v := range list {
+			res.LiveUsers = append(res.LiveUsers, SearchLiveUserItem{
+				Uid:        v.Uid,
+				Uname:      stripHighlight(v.Uname),
+				Face:       v.Uface,
+				Roomid:     v.Roomid,
+				LiveStatus: v.LiveStatus,
+				Attentions: v.Attentions,
+				Tags:       stripHighlight(v.Tags),
+			})
+		}
+	case SearchBiliUser:
+		var list []struct {
+			Mid            int    `json:"mid"`
+			Uname          string `json:"uname"`
+			Upic           string `json:"upic"`
+			Usign          string `json:"usign"`
+			Level          int    `json:"level"`
+			Fans           int    `json:"fans"`
+			Videos         int    `json:"videos"`
+			RoomID         int    `json:"room_id"`
+			IsLive         int    `json:"is_live"`
+			OfficialVerify struct {
+				Type int    `json:"type"`
+				Desc string `json:"desc"`
+			} `json:"official_verify"`
+		}
+		if err = json.Unmarshal(j.Data.Result, &list); err != nil {
+			return
+		}
+		for _, v := range list {
+			res.BiliUsers = append(res.BiliUsers, SearchBiliUserItem{
+				Mid:      v.Mid,
+				Uname:    stripHighlight(v.Uname),
+				Face:     v.Upic,
+				Sign:     stripHighlight(v.Usign),
+				Level:    v.Level,
+				Fans:     v.Fans,
+				Videos:   v.Videos,
+				Roomid:   v.RoomID,
+				IsLive:   v.IsLive == 1,
+				Official: UserOfficial{Title: v.OfficialVerify.Desc, Type: v.OfficialVerify.Type},
+			})
+		}
+	case SearchVideo:
+		var list []struct {
+			Aid         int    `json:"aid"`
+			Bvid        string `json:"bvid"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Author      string `json:"author"`
+			Mid         int    `json:"mid"`
+			Pic         string `json:"pic"`
+			Play        int    `json:"play"`
+			Danmaku     int    `json:"danmaku"`
+			Duration    string `json:"duration"`
+			Pubdate     int64  `json:"pubdate"`
+			TypeName    string `json:"typename"`
+			Tag         string `json:"tag"`
+		}
+		if err = json.Unmarshal(j.Data.Result, &list); err != nil {
+			return
+		}
+		for _, v := range list {
+			res.Videos = append(res.Videos, SearchVideoItem{
+				Aid:         v.Aid,
+				Bvid:        v.Bvid,
+				Title:       stripHighlight(v.Title),
+				Description: stripHighlight(v.Description),
+				Author:      stripHighlight(v.Author),
+				Mid:         v.Mid,
+				Pic:         v.Pic,
+				Play:        v.Play,
+				Danmaku:     v.Danmaku,
+				Duration:    v.Duration,
+				Pubdate:     v.Pubdate,
+				TypeName:    v.TypeName,
+				Tag:         v.Tag,
+			})
+		}
+	}
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
+
+// SearchUP implements biliApiInter.
+func (t *biliApi) SearchUP(s string) (err error, res []struct {
+	Roomid  int
+	Uname   string
+	Is_live bool
+}) {
+	e, r := t.search(`SearchUP`, s, SearchOpt{Type: SearchLiveUser, PageSize: 10, Order: `online`})
+	if e != nil {
+		return e, nil
+	}
+	for _, v := range r.LiveUsers {
+		res = append(res, struct {
+			Roomid  int
+			Uname   string
+			Is_live bool
+		}{
+			Roomid:  v.Roomid,
+			Uname:   v.Uname,
+			Is_live: v.LiveStatus == 1,
+		})
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package biliApi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStripHighlight(t *testing.T) {
+	for s, want := range map[string]string{
+		`<em class="keyword">C酱</em>的直播间`:    `C酱的直播间`,
+		`a &amp; <em class="keyword">b</em>`: `a & b`,
+		`plain`:                              `plain`,
+	} {
+		if got := stripHighlight(s); got != want {
+			t.Fatal(s, got)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != `/x/web-interface/wbi/search/type` {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get(`keyword`) != `C酱 & #1` || q.Get(`w_rid`) == `` {
+			t.Error(q)
+		}
+		switch q.Get(`search_type`) {
+		case `live_room`:
+			if q.Get(`page`) != `2` || q.Get(`page_size`) != `5` || q.Get(`order`) != `live_time` {
+				t.Error(q)
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"numResults":7,"numPages":2,"result":[
+				{"roomid":213,"uid":2,"uname":"<em class=\"keyword\">C酱</em>","title":"t &amp; t","online":100,"live_status":1,"live_time":"2024-01-01 00:00:00","cate_name":"虚拟主播"},
+				{"roomid":214,"uid":3,"uname":"b","title":"t","cate_name":"单机游戏"}
+			]}}`))
+		case `live_user`:
+			_, _ = w.Write([]byte(`{"code":0,"data":{"result":[
+				{"uid":2,"uname":"<em class=\"keyword\">C酱</em>","roomid":213,"live_status":1,"attentions":1000},
+				{"uid":3,"uname":"b","roomid":214,"live_status":0}
+			]}}`))
+		case `bili_user`:
+			if q.Get(`order`) != `fans` || q.Get(`order_sort`) != `1` {
+				t.Error(q)
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"result":[
+				{"mid":2,"uname":"<em class=\"keyword\">C酱</em>","usign":"s","level":6,"fans":1000,"videos":3,"room_id":213,"is_live":1,"official_verify":{"type":0,"desc":"认证"}}
+			]}}`))
+		case `video`:
+			if q.Get(`tids`) != `17` {
+				t.Error(q)
+			}
+			// 无结果时result为空
+			_, _ = w.Write([]byte(`{"code":0,"data":{"numResults":0,"numPages":0,"result":null}}`))
+		default:
+			t.Error(q)
+		}
+	})
+	defer closef()
+	a.wbi.set(`https://i0.hdslb.com/bfs/wbi/a.png`, `https://i0.hdslb.com/bfs/wbi/b.png`, time.Now())
+	var apis []string
+	a.AddMiddleware(Middleware{
+		Before: func(req *ApiReq) error {
+			apis = append(apis, req.Api)
+			return nil
+		},
+	})
+
+	const keyword = `C酱 & #1`
+	e, res := a.Search(keyword, SearchOpt{Type: SearchLiveRoom, Page: 2, PageSize: 5, Order: `live_time`, AreaName: `虚拟主播`})
+	if e != nil || res.NumResults != 7 || res.NumPages != 2 || len(res.LiveRooms) != 1 {
+		t.Fatal(e, res)
+	}
+	if r := res.LiveRooms[0]; r.Roomid != 213 || r.Uname != `C酱` || r.Title != `t & t` || r.AreaName != `虚拟主播` || r.Online != 100 {
+		t.Fatal(r)
+	}
+
+	// 本页均被过滤，数量仍为未过滤的
+	e, res = a.Search(keyword, SearchOpt{Type: SearchLiveRoom, Page: 2, PageSize: 5, Order: `live_time`, AreaName: `手机游戏`})
+	if e != nil || len(res.LiveRooms) != 0 || res.NumResults != 7 || res.NumPages != 2 {
+		t.Fatal(e, res)
+	}
+
+	if e, res := a.Search(keyword, SearchOpt{Type: SearchBiliUser, Order: `fans`, OrderAsc: true}); e != nil || len(res.BiliUsers) != 1 || res.BiliUsers[0].Uname != `C酱` || !res.BiliUsers[0].IsLive || res.BiliUsers[0].Official.Title != `认证` {
+		t.Fatal(e, res)
+	}
+
+	if e, res := a.Search(keyword, SearchOpt{Tids: 17}); e != nil || len(res.Videos) != 0 {
+		t.Fatal(e, res)
+	}
+
+	e, up := a.SearchUP(keyword)
+	if e != nil || len(up) != 2 || up[0].Uname != `C酱` || !up[0].Is_live || up[1].Is_live {
+		t.Fatal(e, up)
+	}
+
+	// SearchUP沿用原名
+	if n := len(apis); n < 2 || apis[n-2] != `Search` || apis[n-1] != `SearchUP` {
+		t.Fatal(apis)
+	}
+}
@@ -0,0 +1,247 @@
+package biliApi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 任务失败后，在此时间后重试
+const taskRetryAfter = 30 * time.Minute
+
+// DailyTask 每日任务，每个账号每天(按SetLocation的时区)成功执行一次
+type DailyTask struct {
+	Name string
+	Run  func(api BiliApi) (err error, result string)
+}
+
+// TaskState 任务执行记录
+type TaskState struct {
+	Day     string    // 最近一次成功的日期，2006-01-02
+	LastRun time.Time // 最近一次执行的时间
+	NextRun time.Time // 下次执行的时间
+	Result  string
+	Err     string // 最近一次执行的错误
+	Count   int    // Day当日已用的额度，用于MedalKeeper
+}
+
+// TaskStore 任务执行记录的存储，需并发安全
+type TaskStore interface {
+	Load(uid int, task string) (err error, state TaskState, ok bool)
+	Save(uid int, task string, state TaskState) (err error)
+}
+
+type memTaskStore struct {
+	m    map[string]TaskState
+	lock sync.Mutex
+}
+
+// NewMemTaskStore 保存在内存中，重启后丢失
+func NewMemTaskStore() TaskStore {
+	return &memTaskStore{m: make(map[string]TaskState)}
+}
+
+func taskKey(uid int, task string) string {
+	return strconv.Itoa(uid) + `/` + task
+}
+
+// Load implements TaskStore.
+func (t *memTaskStore) Load(uid int, task string) (err error, state TaskState, ok bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	state, ok = t.m[taskKey(uid, task)]
+	return
+}
+
+// Save implements TaskStore.
+func (t *memTaskStore) Save(uid int, task string, state TaskState) (err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.m[taskKey(uid, task)] = state
+	return
+}
+
+type fileTaskStore struct {
+	memTaskStore
+	path string
+}
+
+// NewFileTaskStore 以json保存在文件中，文件不存在时新建
+func NewFileTaskStore(path string) (err error, store TaskStore) {
+	t := &fileTaskStore{memTaskStore: memTaskStore{m: make(map[string]TaskState)}, path: path}
+	if b, e := os.ReadFile(path); e == nil {
+		if e := json.Unmarshal(b, &t.m); e != nil {
+			return e, nil
+		}
+	} else if !errors.Is(e, os.ErrNotExist) {
+		return e, nil
+	}
+	return nil, t
+}
+
+// Save implements TaskStore.
+func (t *fileTaskStore) Save(uid int, task string, state TaskState) (err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.m[taskKey(uid, task)] = state
+
+	b, err := json.Marshal(t.m)
+	if err != nil {
+		return
+	}
+	tmp := t.path + `.tmp`
+	if err = os.WriteFile(tmp, b, 0600); err != nil {
+		return
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// TaskRunner 按账号执行每日任务
+type TaskRunner struct {
+	store TaskStore
+	tasks []DailyTask
+	lock  sync.Mutex // 同时只执行一轮
+}
+
+func NewTaskRunner(store TaskStore, tasks ...DailyTask) *TaskRunner {
+	return &TaskRunner{store: store, tasks: tasks}
+}
+
+// Run 执行api账号今天未成功且已到执行时间的任务，返回各任务的记录
+func (t *TaskRunner) Run(api BiliApi) (err error, res map[string]TaskState) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	e, uid := Uid(api)
+	if e != nil {
+		return e, nil
+	}
+	loc := api.GetLocation()
+
+	res = make(map[string]TaskState)
+	for _, task := range t.tasks {
+		e, state, _ := t.store.Load(uid, task.Name)
+		if e != nil {
+			err = errors.Join(err, e)
+			continue
+		}
+
+		now := time.Now()
+		today := now.In(loc).Format(time.DateOnly)
+		if state.Day == today || now.Before(state.NextRun) {
+			res[task.Name] = state
+			continue
+		}
+
+		e, result := task.Run(api)
+		state.LastRun, state.Result = now, result
+		if e != nil {
+			state.Err = e.Error()
+			state.NextRun = now.Add(taskRetryAfter)
+			err = errors.Join(err, fmt.Errorf("%s: %w", task.Name, e))
+		} else {
+			y, m, d := now.In(loc).Date()
+			state.Day, state.Err = today, ``
+			state.NextRun = time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+		}
+		if e := t.store.Save(uid, task.Name, state); e != nil {
+			err = errors.Join(err, e)
+		}
+		res[task.Name] = state
+	}
+	return
+}
+
+// Start 每interval对accounts中的所有账号执行一次Run，直至ctx结束，errf可为nil
+func (t *TaskRunner) Start(ctx context.Context, accounts *Accounts, interval time.Duration, errf func(uid int, err error)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			accounts.Range(func(uid int, api BiliApi) bool {
+				if e, _ := t.Run(api); e != nil && errf != nil {
+					errf(uid, e)
+				}
+				return ctx.Err() == nil
+			})
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// TaskDoSign 直播签到
+func TaskDoSign() DailyTask {
+	return DailyTask{
+		Name: `DoSign`,
+		Run: func(api BiliApi) (err error, result string) {
+			if e, status := api.GetWebGetSignInfo(); e != nil {
+				return e, ``
+			} else if status == 1 {
+				return nil, `已签到`
+			}
+			if e, days := api.DoSign(); e != nil {
+				return e, ``
+			} else {
+				return nil, fmt.Sprintf("签到成功，本月已签%d天", days)
+			}
+		},
+	}
+}
+
+// TaskSilver2coin 银瓜子换硬币
+func TaskSilver2coin() DailyTask {
+	return DailyTask{
+		Name: `Silver2coin`,
+		Run: func(api BiliApi) (err error, result string) {
+			e, wallet := api.GetWalletStatus()
+			if e != nil {
+				return e, ``
+			} else if wallet.Silver2CoinLeft <= 0 {
+				return nil, `今日已兑换`
+			}
+			if e, price := api.GetWalletRule(); e != nil {
+				return e, ``
+			} else if wallet.Silver < price {
+				return nil, fmt.Sprintf("银瓜子不足%d", price)
+			}
+			return api.Silver2coin()
+		},
+	}
+}
+
+// TaskLightMedals 点亮粉丝牌
+func TaskLightMedals(keeper *MedalKeeper) DailyTask {
+	return DailyTask{
+		Name: `LightMedals`,
+		Run: func(api BiliApi) (err error, result string) {
+			e, res := keeper.Run(api, nil)
+			var done int
+			for _, p := range res {
+				if len(p.Done) > 0 {
+					done += 1
+				}
+			}
+			return e, fmt.Sprintf("处理%d个", done)
+		},
+	}
+}
+
+// TaskExpiringGifts 将within内过期的背包礼物赠送至直播间
+func TaskExpiringGifts(roomid, upUid int, within time.Duration) DailyTask {
+	return DailyTask{
+		Name: `ExpiringGifts`,
+		Run: func(api BiliApi) (err error, result string) {
+			e, res := api.SendExpiringBagGifts(roomid, upUid, within)
+			return e, fmt.Sprintf("赠送%d项", len(res))
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package biliApi
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTaskRunner(t *testing.T) {
+	a := newBiliApi(newReqPool())
+	a.SetCookies([]*http.Cookie{{Name: `DedeUserID`, Value: `1`}})
+	a.SetLocation(8 * 3600)
+
+	var ok, fail int
+	tasks := []DailyTask{
+		{Name: `ok`, Run: func(api biliApiInter) (err error, result string) {
+			ok += 1
+			return nil, `done`
+		}},
+		{Name: `fail`, Run: func(api biliApiInter) (err error, result string) {
+			fail += 1
+			return errors.New(`fail`), ``
+		}},
+	}
+
+	path := filepath.Join(t.TempDir(), `task.json`)
+	e, store := NewFileTaskStore(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	e, res := NewTaskRunner(store, tasks...).Run(a)
+	if e == nil || ok != 1 || fail != 1 {
+		t.Fatal(e, ok, fail)
+	}
+	now := time.Now().In(a.GetLocation())
+	y, m, d := now.Date()
+	if s := res[`ok`]; s.Day != now.Format(time.DateOnly) || s.Result != `done` || !s.NextRun.Equal(time.Date(y, m, d+1, 0, 0, 0, 0, a.GetLocation())) {
+		t.Fatal(s)
+	}
+	if s := res[`fail`]; s.Day != `` || s.Err != `fail` || s.NextRun.Sub(s.LastRun) != taskRetryAfter {
+		t.Fatal(s)
+	}
+
+	// 重启后不再重复执行
+	e, store = NewFileTaskStore(path)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if e, res := NewTaskRunner(store, tasks...).Run(a); e != nil || ok != 1 || fail != 1 || res[`ok`].Result != `done` {
+		t.Fatal(e, ok, fail)
+	}
+
+	// 未登录
+	if e, _ := NewTaskRunner(NewMemTaskStore(), tasks...).Run(newBiliApi(newReqPool())); !errors.Is(e, ErrNoUid) {
+		t.Fatal(e)
+	}
+}
@@ -0,0 +1,265 @@
+package biliApi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	reqf "github.com/qydysky/part/reqf"
+)
+
+// UserOfficial 认证信息
+type UserOfficial struct {
+	Role  int    // 0无认证
+	Title string // 认证说明
+	Type  int    // -1无 0个人 1机构
+}
+
+// UserCard 用户名片
+type UserCard struct {
+	Mid       int
+	Name      string
+	Face      string
+	Sex       string
+	Sign      string
+	Level     int
+	Fans      int
+	Attention int // 关注数
+	Official  UserOfficial
+	Following bool // 自己是否已关注，未登录时为false
+}
+
+// SpaceLiveRoom 空间中的直播间信息
+type SpaceLiveRoom struct {
+	Roomid     int // 0为未开通直播间
+	LiveStatus int // 1直播中
+	Title      string
+	Cover      string
+	Url        string
+}
+
+// SpaceInfo 用户空间信息
+type SpaceInfo struct {
+	Mid      int
+	Name     string
+	Face     string
+	Sex      string
+	Sign     string
+	Level    int
+	Birthday string // 如 01-01，未公开时为空
+	Official UserOfficial
+	LiveRoom SpaceLiveRoom
+}
+
+type userOfficialJson struct {
+	Role  int    `json:"role"`
+	Title string `json:"title"`
+	Type  int    `json:"type"`
+}
+
+// GetUserCard implements biliApiInter.
+func (t *biliApi) GetUserCard(uid int) (err error, res UserCard) {
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &res, `GetUserCard`, uid); hit {
+		return e, res
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetUserCard", reqf.Rval{
+		Url: "https://api.bilibili.com/x/web-interface/card?photo=false&mid=" + strconv.Itoa(uid),
+		Header: map[string]string{
+			`Referer`: fmt.Sprintf("https://space.bilibili.com/%d", uid),
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Card struct {
+				Name      string `json:"name"`
+				Face      string `json:"face"`
+				Sex       string `json:"sex"`
+				Sign      string `json:"sign"`
+				Fans      int    `json:"fans"`
+				Attention int    `json:"attention"`
+				LevelInfo struct {
+					CurrentLevel int `json:"current_level"`
+				} `json:"level_info"`
+				Official userOfficialJson `json:"Official"`
+			} `json:"card"`
+			Following bool `json:"following"`
+			Follower  int  `json:"follower"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	}
+
+	card := j.Data.Card
+	res = UserCard{
+		Mid:       uid,
+		Name:      card.Name,
+		Face:      card.Face,
+		Sex:       card.Sex,
+		Sign:      card.Sign,
+		Level:     card.LevelInfo.CurrentLevel,
+		Fans:      card.Fans,
+		Attention: card.Attention,
+		Official:  UserOfficial(card.Official),
+		Following: j.Data.Following,
+	}
+	// card.fans可能为0
+	if j.Data.Follower > res.Fans {
+		res.Fans = j.Data.Follower
+	}
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
+
+// GetSpaceInfo implements biliApiInter.
+func (t *biliApi) GetSpaceInfo(uid int) (err error, res SpaceInfo) {
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &res, `GetSpaceInfo`, uid); hit {
+		return e, res
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	query := "mid=" + strconv.Itoa(uid)
+	if e, queryE := t.wbiSign(query); e != nil {
+		err = e
+		return
+	} else {
+		query = queryE
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetSpaceInfo", reqf.Rval{
+		Url: "https://api.bilibili.com/x/space/wbi/acc/info?" + query,
+		Header: map[string]string{
+			`Origin`:  `https://space.bilibili.com`,
+			`Referer`: fmt.Sprintf("https://space.bilibili.com/%d", uid),
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Mid      int              `json:"mid"`
+			Name     string           `json:"name"`
+			Face     string           `json:"face"`
+			Sex      string           `json:"sex"`
+			Sign     string           `json:"sign"`
+			Level    int              `json:"level"`
+			Birthday string           `json:"birthday"`
+			Official userOfficialJson `json:"official"`
+			LiveRoom struct {
+				Roomid     int    `json:"roomid"`
+				LiveStatus int    `json:"liveStatus"`
+				Title      string `json:"title"`
+				Cover      string `json:"cover"`
+				Url        string `json:"url"`
+			} `json:"live_room"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		if j.Code == -352 {
+			t.wbi.invalidate()
+		}
+		err = errors.New(j.Message)
+		return
+	}
+
+	res = SpaceInfo{
+		Mid:      j.Data.Mid,
+		Name:     j.Data.Name,
+		Face:     j.Data.Face,
+		Sex:      j.Data.Sex,
+		Sign:     j.Data.Sign,
+		Level:    j.Data.Level,
+		Birthday: j.Data.Birthday,
+		Official: UserOfficial(j.Data.Official),
+		LiveRoom: SpaceLiveRoom(j.Data.LiveRoom),
+	}
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
+
+// GetRoomidByUid implements biliApiInter.
+func (t *biliApi) GetRoomidByUid(uid int) (err error, roomid int) {
+	c := t.config()
+	if e, hit, done := cacheDo(t, c, &roomid, `GetRoomidByUid`, uid); hit {
+		return e, roomid
+	} else if done != nil {
+		defer func() { done(err) }()
+	}
+
+	req := c.pool.Get()
+	defer c.pool.Put(req)
+	err = t.do(c, req, "GetRoomidByUid", reqf.Rval{
+		Url: "https://api.live.bilibili.com/room/v1/Room/getRoomInfoOld?mid=" + strconv.Itoa(uid),
+		Header: map[string]string{
+			`Referer`: fmt.Sprintf("https://space.bilibili.com/%d", uid),
+		},
+		Timeout: 5 * 1000,
+	})
+	if err != nil {
+		return
+	}
+
+	var j struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    struct {
+			Roomid int `json:"roomid"`
+		} `json:"data"`
+	}
+
+	err = req.ResponUnmarshal(json.Unmarshal, &j)
+	if err != nil {
+		return
+	} else if j.Code != 0 {
+		err = errors.New(j.Message)
+		return
+	}
+	roomid = j.Data.Roomid
+
+	req.Response(func(r *http.Response) error {
+		t.setRespCookies(r)
+		return nil
+	})
+	return
+}
@@ -0,0 +1,58 @@
+package biliApi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUser(t *testing.T) {
+	var n int
+	a, closef := newTestApi(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch r.URL.Path {
+		case `/x/web-interface/card`:
+			if q.Get(`mid`) != `2` {
+				t.Error(q)
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"card":{"mid":"2","name":"碧诗","face":"https://i0.hdslb.com/a.jpg","sex":"男","sign":"s","fans":0,"attention":46,"level_info":{"current_level":6},"Official":{"role":2,"title":"bilibili创始人","type":0}},"following":true,"follower":1000}}`))
+		case `/x/space/wbi/acc/info`:
+			if q.Get(`mid`) != `2` || q.Get(`w_rid`) == `` || q.Get(`wts`) == `` {
+				t.Error(q)
+			}
+			_, _ = w.Write([]byte(`{"code":0,"data":{"mid":2,"name":"碧诗","face":"https://i0.hdslb.com/a.jpg","sex":"男","sign":"s","level":6,"birthday":"01-01","official":{"role":2,"title":"bilibili创始人","type":0},"live_room":{"roomStatus":1,"liveStatus":0,"url":"https://live.bilibili.com/213","title":"t","cover":"https://i0.hdslb.com/c.jpg","roomid":213}}}`))
+		case `/room/v1/Room/getRoomInfoOld`:
+			n += 1
+			if q.Get(`mid`) == `2` {
+				_, _ = w.Write([]byte(`{"code":0,"data":{"roomStatus":1,"roomid":213}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"code":0,"data":{"roomStatus":0,"roomid":0}}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer closef()
+	a.wbi.set(`https://i0.hdslb.com/bfs/wbi/a.png`, `https://i0.hdslb.com/bfs/wbi/b.png`, time.Now())
+
+	e, card := a.GetUserCard(2)
+	if e != nil || card.Mid != 2 || card.Name != `碧诗` || card.Level != 6 || card.Fans != 1000 || card.Attention != 46 || card.Official.Role != 2 || !card.Following {
+		t.Fatal(e, card)
+	}
+
+	e, info := a.GetSpaceInfo(2)
+	if e != nil || info.Mid != 2 || info.Level != 6 || info.Birthday != `01-01` || info.Official.Title != `bilibili创始人` || info.LiveRoom.Roomid != 213 || info.LiveRoom.Url == `` {
+		t.Fatal(e, info)
+	}
+
+	if e, roomid := a.GetRoomidByUid(2); e != nil || roomid != 213 {
+		t.Fatal(e, roomid)
+	}
+	if e, roomid := a.GetRoomidByUid(3); e != nil || roomid != 0 {
+		t.Fatal(e, roomid)
+	}
+	// 缓存
+	if e, roomid := a.GetRoomidByUid(2); e != nil || roomid != 213 || n != 2 {
+		t.Fatal(e, roomid, n)
+	}
+}
@@ -0,0 +1,162 @@
+package biliApi
+
+import (
+	"crypto/md5"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bilibili服务端所在时区，wbi key按此时区每日轮换
+var biliLocation = time.FixedZone("CST", 8*3600)
+
+var wbiMixinKeyEncTab = []int{46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35, 27, 43, 5,
+	49, 33, 9, 42, 19, 29, 28, 14, 39, 12, 38, 41, 13, 37, 48, 7, 16, 24, 55,
+	40, 61, 26, 17, 0, 1, 60, 51, 30, 4, 22, 25, 54, 21, 56, 59, 6, 63, 57,
+	62, 11, 36, 20, 34, 44, 52}
+
+// wbiSigner 缓存img_key/sub_key至其轮换
+type wbiSigner struct {
+	imgKey  string
+	subKey  string
+	expires time.Time
+	lock    sync.RWMutex
+}
+
+// get 获取未过期的key
+func (t *wbiSigner) get(now time.Time) (imgKey, subKey string, ok bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.imgKey == `` || t.subKey == `` || !now.Before(t.expires) {
+		return
+	}
+	return t.imgKey, t.subKey, true
+}
+
+// set 保存key，有效至下一次轮换(次日0点)
+func (t *wbiSigner) set(imgURL, subURL string, now time.Time) {
+	imgKey, subKey := wbiKey(imgURL), wbiKey(subURL)
+	if imgKey == `` || subKey == `` {
+		return
+	}
+	y, m, d := now.In(biliLocation).Date()
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.imgKey, t.subKey = imgKey, subKey
+	t.expires = time.Date(y, m, d+1, 0, 0, 0, 0, biliLocation)
+}
+
+// invalidate 使key失效，下次签名时重新获取
+func (t *wbiSigner) invalidate() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.expires = time.Time{}
+}
+
+// wbiSign 使用缓存的key对query签名，key失效时通过GetNav获取
+func (t *biliApi) wbiSign(query string) (err error, queryEnc string) {
+	imgKey, subKey, ok := t.wbi.get(time.Now())
+	if !ok {
+		t.respCache.invalidate(`GetNav`)
+		if e, v := t.GetNav(); e != nil {
+			return e, ``
+		} else {
+			imgKey, subKey = wbiKey(v.WbiImg.ImgURL), wbiKey(v.WbiImg.SubURL)
+		}
+	}
+	if imgKey == `` || subKey == `` {
+		return ErrWbiKey, ``
+	}
+	queryEnc, _, _ = getWridWts(query, imgKey, subKey)
+	return
+}
+
+// WbiRefresh implements biliApiInter.
+func (t *biliApi) WbiRefresh() (err error) {
+	t.wbi.invalidate()
+	err, _ = t.wbiSign(``)
+	return
+}
+
+// wbiKey 从img_url/sub_url中取得key，已是key时原样返回
+func wbiKey(u string) string {
+	if i := strings.LastIndex(u, "/"); i >= 0 {
+		u = u[i+1:]
+	}
+	if i := strings.LastIndex(u, "."); i >= 0 {
+		u = u[:i]
+	}
+	return u
+}
+
+// wbiEncode 同js encodeURIComponent
+func wbiEncode(s string) string {
+	return strings.NewReplacer(`+`, `%20`, `%21`, `!`, `%27`, `'`, `%28`, `(`, `%29`, `)`, `%2A`, `*`).Replace(url.QueryEscape(s))
+}
+
+// bilibili wrid wts 计算
+//
+// query为已编码的查询字符串，返回按key排序、按web端规则重新编码并附加wts、w_rid后的queryEnc
+func getWridWts(query string, imgURL, subURL string, customWts ...string) (queryEnc, w_rid, wts string) {
+	wbi := wbiKey(imgURL) + wbiKey(subURL)
+
+	s := []byte{}
+
+	for i := 0; i < len(wbiMixinKeyEncTab); i++ {
+		if wbiMixinKeyEncTab[i] < len(wbi) {
+			s = append(s, wbi[wbiMixinKeyEncTab[i]])
+			if len(s) >= 32 {
+				break
+			}
+		}
+	}
+
+	if len(customWts) == 0 {
+		wts = fmt.Sprintf("%d", time.Now().Unix())
+	} else {
+		wts = customWts[0]
+	}
+
+	var object [][2]string
+	for _, kv := range strings.Split(query, "&") {
+		if kv == `` {
+			continue
+		}
+		k, v, _ := strings.Cut(kv, "=")
+		if uk, e := url.QueryUnescape(k); e == nil {
+			k = uk
+		}
+		if uv, e := url.QueryUnescape(v); e == nil {
+			v = uv
+		}
+		if k == `w_rid` || k == `wts` {
+			continue
+		}
+		object = append(object, [2]string{k, v})
+	}
+	object = append(object, [2]string{`wts`, wts})
+
+	slices.SortStableFunc(object, func(a, b [2]string) int {
+		return strings.Compare(a[0], b[0])
+	})
+
+	items := make([]string, len(object))
+	for i := 0; i < len(object); i++ {
+		v := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`!'()*`, r) {
+				return -1
+			}
+			return r
+		}, object[i][1])
+		items[i] = wbiEncode(object[i][0]) + "=" + wbiEncode(v)
+	}
+	queryEnc = strings.Join(items, "&")
+
+	w_rid = fmt.Sprintf("%x", md5.Sum([]byte(queryEnc+string(s))))
+	queryEnc += "&w_rid=" + w_rid
+
+	return
+}
@@ -0,0 +1,59 @@
+package biliApi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetWridWts(t *testing.T) {
+	const (
+		imgURL = `https://i0.hdslb.com/bfs/wbi/7cd084941338484aae1ad9425b84077c.png`
+		subURL = `https://i0.hdslb.com/bfs/wbi/4932caff0ff746eab6f01bf08b70ac45.png`
+		wts    = `1702204169`
+	)
+	for _, v := range []struct {
+		query    string
+		queryEnc string
+	}{
+		{
+			`foo=114&bar=514&zab=1919810`,
+			`bar=514&foo=114&wts=1702204169&zab=1919810&w_rid=8f6f2b5b3d485fe1886cec6a0be8c5d4`,
+		},
+		{
+			`foo=one%20one%20four&bar=五一四&baz=1919810!()*'`,
+			`bar=%E4%BA%94%E4%B8%80%E5%9B%9B&baz=1919810&foo=one%20one%20four&wts=1702204169&w_rid=04e50b58980e3e3cee8cbc0cc4c1c530`,
+		},
+		{
+			`search_type=live_user&keyword=C%E9%85%B1+%26+%231&wts=1&w_rid=x`,
+			`keyword=C%E9%85%B1%20%26%20%231&search_type=live_user&wts=1702204169&w_rid=f96ebde70eb5b9c1549c4807b1d23867`,
+		},
+	} {
+		if queryEnc, _, _ := getWridWts(v.query, imgURL, subURL, wts); queryEnc != v.queryEnc {
+			t.Fatal(v.query, queryEnc)
+		}
+	}
+
+	if a, _, _ := getWridWts(`foo=114&bar=514&zab=1919810`, wbiKey(imgURL), wbiKey(subURL), wts); a[len(a)-32:] != `8f6f2b5b3d485fe1886cec6a0be8c5d4` {
+		t.Fatal(a)
+	}
+}
+
+func TestWbiSigner(t *testing.T) {
+	var s wbiSigner
+	now := time.Date(2024, 1, 1, 23, 0, 0, 0, biliLocation)
+	if _, _, ok := s.get(now); ok {
+		t.Fatal()
+	}
+	s.set(`https://i0.hdslb.com/bfs/wbi/a.png`, `https://i0.hdslb.com/bfs/wbi/b.png`, now)
+	if img, sub, ok := s.get(now.Add(59 * time.Minute)); !ok || img != `a` || sub != `b` {
+		t.Fatal(img, sub)
+	}
+	if _, _, ok := s.get(now.Add(time.Hour)); ok {
+		t.Fatal()
+	}
+	s.set(`a`, `b`, now)
+	s.invalidate()
+	if _, _, ok := s.get(now); ok {
+		t.Fatal()
+	}
+}